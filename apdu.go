@@ -3,8 +3,12 @@
 package apdu
 
 import (
+	"bytes"
+	"encoding/base64"
 	"encoding/binary"
 	"encoding/hex"
+	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -42,11 +46,20 @@ const (
 	// LenLCExtended defines the length of the LC of an extended length APDU.
 	LenLCExtended int = 3
 	// LenResponseTrailer defines the length of the trailer of a Response APDU.
-	LenResponseTrailer int    = 2
-	packageTag         string = "skythen/apdu"
+	LenResponseTrailer int = 2
+	// MaxLenCapdu defines the maximum length of a Capdu (extended length Case 4 with maximum data length).
+	MaxLenCapdu int = LenHeader + LenLCExtended + MaxLenCommandDataExtended + 2
+	// MaxLenRapdu defines the maximum length of a RAPDU (maximum extended response data length and trailer).
+	MaxLenRapdu int    = MaxLenResponseDataExtended + LenResponseTrailer
+	packageTag  string = "skythen/apdu"
 )
 
 // Capdu is a Command APDU.
+//
+// Ne is the number of expected response data bytes and not the encoded Le: Ne of 0 means that no response data is
+// expected and results in a Case 1 or Case 3 command without Le. To request the maximum of 256 bytes of the standard
+// length format (Le '00'), Ne must be set to 256, and to 65536 for the extended length format (Le '0000').
+// ParseCapdu decodes these Le values back to 256 and 65536, so Ne round trips through Bytes and ParseCapdu.
 type Capdu struct {
 	Cla  byte   // Cla is the class byte.
 	Ins  byte   // Ins is the instruction byte.
@@ -54,28 +67,189 @@ type Capdu struct {
 	P2   byte   // P2 is the p2 byte.
 	Data []byte // Data is the data field.
 	Ne   int    // Ne is the total number of expected response data byte (not LE encoded).
+	// Meta is arbitrary metadata for the use of the caller, e.g. correlation IDs in a middleware pipeline. It is not
+	// part of the Command APDU and is ignored by Bytes, Equal and all other functions of the package. See SetMeta.
+	Meta map[string]interface{}
 }
 
 // ParseCapdu parses a Command APDU and returns a Capdu.
+// A body of three zero bytes is parsed as extended length Case 2 command with Ne of 65536. Any longer body with an
+// extended length Lc of zero is rejected, since ISO 7816-4 does not allow an extended Lc to indicate empty data.
 func ParseCapdu(c []byte) (*Capdu, error) {
-	if len(c) < LenHeader || len(c) > 65544 {
-		return nil, errors.Errorf("%s: invalid length - Capdu must consist of at least 4 byte and maximum of 65544 byte, got %d", packageTag, len(c))
+	if len(c) < LenHeader || len(c) > MaxLenCapdu {
+		return nil, errors.Errorf("%s: invalid length - Capdu must consist of at least %d byte and maximum of %d byte, got %d", packageTag, LenHeader, MaxLenCapdu, len(c))
 	}
 
+	return parseCapdu(c[:LenHeader], c[LenHeader:])
+}
+
+// ParseCapduWithRaw calls ParseCapdu and returns the Capdu together with a copy of c. The copy preserves the exact
+// encoding of the command, which might differ from the result of Bytes, e.g. if a standard length command was encoded
+// in extended length format.
+func ParseCapduWithRaw(c []byte) (*Capdu, []byte, error) {
+	capdu, err := ParseCapdu(c)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	raw := make([]byte, len(c))
+	copy(raw, c)
+
+	return capdu, raw, nil
+}
+
+// ParseCapduWithLengthFormat calls ParseCapdu and returns the Capdu together with true if c uses the extended length
+// format, else false. In contrast to IsExtendedLength, which reports whether the Capdu requires the extended length
+// format, this reflects the format that was actually used, e.g. for a standard length command encoded in extended
+// length format.
+func ParseCapduWithLengthFormat(c []byte) (*Capdu, bool, error) {
+	capdu, err := ParseCapdu(c)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// a successfully parsed body that starts with a zero byte and consists of more than one byte has an extended
+	// length Lc or Le, since a standard length Lc must not be zero
+	extended := len(c) > LenHeader+1 && c[OffsetLcStandard] == 0x00
+
+	return capdu, extended, nil
+}
+
+// ParseCapduBestEffort parses a possibly malformed Command APDU, e.g. from a corrupted capture, and returns the most
+// likely Capdu together with warnings that describe the inconsistencies that were worked around.
+// Well-formed input is parsed like ParseCapdu and yields no warnings. Otherwise, the following heuristics are applied:
+//   - input exceeding MaxLenCapdu is truncated to MaxLenCapdu
+//   - the body is considered extended length if it starts with a zero byte and consists of at least 3 byte
+//   - an Lc of zero is invalid and interpreted as Le of a Case 2 command, any further bytes are ignored
+//   - if Lc indicates more data than available, the data length is assumed from the buffer and no Le is present
+//   - if Lc indicates less data than available, the bytes after the data are interpreted as Le and any further
+//     bytes are ignored
+//
+// An error is only returned if the input is shorter than the header.
+func ParseCapduBestEffort(c []byte) (*Capdu, []string, error) {
+	if len(c) < LenHeader {
+		return nil, nil, errors.Errorf("%s: invalid length - Capdu must consist of at least %d byte, got %d", packageTag, LenHeader, len(c))
+	}
+
+	var warnings []string
+
+	if len(c) > MaxLenCapdu {
+		warnings = append(warnings, fmt.Sprintf("length of %d byte exceeds maximum of %d byte, ignored %d trailing byte", len(c), MaxLenCapdu, len(c)-MaxLenCapdu))
+		c = c[:MaxLenCapdu]
+	}
+
+	if capdu, err := ParseCapdu(c); err == nil {
+		return capdu, warnings, nil
+	}
+
+	capdu := &Capdu{Cla: c[OffsetCla], Ins: c[OffsetIns], P1: c[OffsetP1], P2: c[OffsetP2]}
+	body := c[LenHeader:]
+
+	lenLc, lenLe := LenLCStandard, 1
+	if body[0] == 0x00 && len(body) >= LenLCExtended {
+		lenLc, lenLe = LenLCExtended, 2
+	}
+
+	lc := int(body[0])
+	if lenLc == LenLCExtended {
+		lc = int(binary.BigEndian.Uint16(body[1:LenLCExtended]))
+	}
+
+	if lc == 0 {
+		capdu.Ne = decodeLe(body[:lenLc])
+		warnings = append(warnings, "Lc of zero is invalid, assumed Le of Case 2 command")
+
+		if len(body) > lenLc {
+			warnings = append(warnings, fmt.Sprintf("ignored %d trailing byte", len(body)-lenLc))
+		}
+
+		return capdu, warnings, nil
+	}
+
+	available := len(body) - lenLc
+	if lc > available {
+		capdu.Data = body[lenLc:]
+		warnings = append(warnings, fmt.Sprintf("Lc mismatch - Lc indicates %d byte, but %d byte available, assumed data length from buffer", lc, available))
+
+		return capdu, warnings, nil
+	}
+
+	capdu.Data = body[lenLc : lenLc+lc]
+	rest := body[lenLc+lc:]
+
+	warnings = append(warnings, fmt.Sprintf("Lc mismatch - Lc indicates %d byte, but %d byte available, assumed data length from Lc", lc, available))
+
+	if len(rest) >= lenLe {
+		capdu.Ne = decodeLe(rest[:lenLe])
+		rest = rest[lenLe:]
+	}
+
+	if len(rest) > 0 {
+		warnings = append(warnings, fmt.Sprintf("ignored %d trailing byte", len(rest)))
+	}
+
+	return capdu, warnings, nil
+}
+
+// decodeLe returns Ne for the given Le field of one, two or three byte.
+func decodeLe(le []byte) int {
+	if len(le) == 1 {
+		if le[0] == 0x00 {
+			return MaxLenResponseDataStandard
+		}
+
+		return int(le[0])
+	}
+
+	ne := int(binary.BigEndian.Uint16(le[len(le)-2:]))
+	if ne == 0 {
+		return MaxLenResponseDataExtended
+	}
+
+	return ne
+}
+
+// ParseCapduParts parses a Command APDU whose header and body (Lc, data and Le) are supplied separately and returns
+// a Capdu. The body is empty for Case 1 commands.
+func ParseCapduParts(header, body []byte) (*Capdu, error) {
+	if len(header) != LenHeader {
+		return nil, errors.Errorf("%s: invalid length of header - must consist of 4 byte, got %d", packageTag, len(header))
+	}
+
+	if len(body) > MaxLenCapdu-LenHeader {
+		return nil, errors.Errorf("%s: invalid length of body - must consist of maximum of %d byte, got %d", packageTag, MaxLenCapdu-LenHeader, len(body))
+	}
+
+	return parseCapdu(header, body)
+}
+
+// parseCapdu parses a Command APDU consisting of the 4 byte header and the body that follows the header.
+func parseCapdu(header, body []byte) (*Capdu, error) {
+	cla, ins, p1, p2 := header[OffsetCla], header[OffsetIns], header[OffsetP1], header[OffsetP2]
+
 	// CASE 1 command: only HEADER
-	if len(c) == LenHeader {
-		return &Capdu{Cla: c[OffsetCla], Ins: c[OffsetIns], P1: c[OffsetP1], P2: c[OffsetP2]}, nil
+	if len(body) == 0 {
+		return &Capdu{Cla: cla, Ins: ins, P1: p1, P2: p2}, nil
 	}
 
+	// an extended Le that follows command data (CASE 4) has no leading zero byte
+	const lenLeExtendedCase4 = 2
+
+	// offsets relative to the beginning of the body
+	offsetLcStandard := OffsetLcStandard - LenHeader
+	offsetLcExtended := OffsetLcExtended - LenHeader
+	offsetCdataStandard := OffsetCdataStandard - LenHeader
+	offsetCdataExtended := OffsetCdataExtended - LenHeader
+
 	// check for zero byte
-	if c[OffsetLcStandard] == 0x00 {
+	if body[offsetLcStandard] == 0x00 {
 		// check for extended length Capdu
-		if len(c[OffsetLcExtended:]) > 0 {
+		if len(body[offsetLcExtended:]) > 0 {
 			// EXTENDED CASE 2 command: HEADER | LE
 			// in this case no LC is present, but the two byte LE with leading zero byte
-			if len(c) == LenHeader+LenLCExtended {
+			if len(body) == LenLCExtended {
 				ne := 0
-				le := int(binary.BigEndian.Uint16(c[OffsetLcExtended:]))
+				le := int(binary.BigEndian.Uint16(body[offsetLcExtended:]))
 
 				if le == 0x00 {
 					ne = MaxLenResponseDataExtended
@@ -83,27 +257,43 @@ func ParseCapdu(c []byte) (*Capdu, error) {
 					ne = le
 				}
 
-				return &Capdu{Cla: c[OffsetCla], Ins: c[OffsetIns], P1: c[OffsetP1], P2: c[OffsetP2], Ne: ne}, nil
+				return &Capdu{Cla: cla, Ins: ins, P1: p1, P2: p2, Ne: ne}, nil
 			}
 
-			bodyLen := len(c) - LenHeader
+			// a body of two byte can neither be an extended Lc nor an extended Le
+			if len(body) < LenLCExtended {
+				return nil, errors.Errorf("%s: invalid length of extended body - must consist of at least 3 byte, got %d", packageTag, len(body))
+			}
+
+			bodyLen := len(body)
+
+			// an extended Lc consists of a zero byte followed by two bytes that must not both be zero, hence
+			// '00 00 00' can only be an extended Le, which is handled above (EXTENDED CASE 2)
+			lc := int(binary.BigEndian.Uint16(body[offsetLcExtended : offsetLcExtended+2]))
+			if lc == 0 {
+				return nil, errors.Errorf("%s: invalid LC value - extended LC must not be zero", packageTag)
+			}
+
+			// the data following the Lc either fills the rest of the body (CASE 3) or is followed by a two byte Le
+			// (CASE 4), which is why the length of the Le is subtracted from the remaining length for the latter
+			lenDataCase3 := bodyLen - LenLCExtended
+			lenDataCase4 := lenDataCase3 - lenLeExtendedCase4
 
-			lc := int(binary.BigEndian.Uint16(c[OffsetLcExtended : OffsetLcExtended+2]))
-			if lc != bodyLen-LenLCExtended && lc != bodyLen-LenLCExtended-2 {
-				return nil, errors.Errorf("%s: invalid LC value - LC indicates data length %d", packageTag, lc)
+			if lc != lenDataCase3 && lc != lenDataCase4 {
+				return nil, errors.Errorf("%s: invalid LC value - LC indicates data length %d, but %d byte available after LC", packageTag, lc, lenDataCase3)
 			}
 
-			data := c[OffsetCdataExtended : OffsetCdataExtended+lc]
+			data := body[offsetCdataExtended : offsetCdataExtended+lc]
 
 			// EXTENDED CASE 3 command: HEADER | LC | DATA
-			if len(c) == LenHeader+LenLCExtended+len(data) {
-				return &Capdu{Cla: c[OffsetCla], Ins: c[OffsetIns], P1: c[OffsetP1], P2: c[OffsetP2], Data: data, Ne: 0}, nil
+			if lc == lenDataCase3 {
+				return &Capdu{Cla: cla, Ins: ins, P1: p1, P2: p2, Data: data, Ne: 0}, nil
 			}
 
 			// EXTENDED CASE 4 command: HEADER | LC | DATA | LE
 			ne := 0
 
-			le := int(binary.BigEndian.Uint16(c[len(c)-2:]))
+			le := int(binary.BigEndian.Uint16(body[bodyLen-lenLeExtendedCase4:]))
 
 			if le == 0x00 {
 				ne = MaxLenResponseDataExtended
@@ -111,55 +301,97 @@ func ParseCapdu(c []byte) (*Capdu, error) {
 				ne = le
 			}
 
-			return &Capdu{Cla: c[OffsetCla], Ins: c[OffsetIns], P1: c[OffsetP1], P2: c[OffsetP2], Data: data, Ne: ne}, nil
+			return &Capdu{Cla: cla, Ins: ins, P1: p1, P2: p2, Data: data, Ne: ne}, nil
 		}
 	}
 
 	ne := 0
 	// STANDARD CASE 2 command: HEADER | LE
-	if len(c) == LenHeader+LenLCStandard {
+	if len(body) == LenLCStandard {
 		// in this case, no LC is present
-		ne = int(c[OffsetLcStandard])
+		ne = int(body[offsetLcStandard])
 		if ne == 0 {
-			return &Capdu{Cla: c[OffsetCla], Ins: c[OffsetIns], P1: c[OffsetP1], P2: c[OffsetP2], Data: nil, Ne: MaxLenResponseDataStandard}, nil
+			return &Capdu{Cla: cla, Ins: ins, P1: p1, P2: p2, Data: nil, Ne: MaxLenResponseDataStandard}, nil
 		}
 
-		return &Capdu{Cla: c[OffsetCla], Ins: c[OffsetIns], P1: c[OffsetP1], P2: c[OffsetP2], Data: nil, Ne: ne}, nil
+		return &Capdu{Cla: cla, Ins: ins, P1: p1, P2: p2, Data: nil, Ne: ne}, nil
 	}
 
-	bodyLen := len(c) - LenHeader
+	bodyLen := len(body)
 
 	// check if lc indicates valid length
-	lc := int(c[OffsetLcStandard])
+	lc := int(body[offsetLcStandard])
 	if lc != bodyLen-LenLCStandard && lc != bodyLen-LenLCStandard-1 {
-		return nil, errors.Errorf("%s: invalid Lc value - Lc indicates length %d", packageTag, lc)
+		return nil, errors.Errorf("%s: invalid Lc value - Lc indicates length %d, but %d byte available after Lc", packageTag, lc, bodyLen-LenLCStandard)
 	}
 
-	data := c[OffsetCdataStandard : OffsetCdataStandard+lc]
+	data := body[offsetCdataStandard : offsetCdataStandard+lc]
 
 	// STANDARD CASE 3 command: HEADER | LC | DATA
-	if len(c) == LenHeader+LenLCStandard+len(data) {
-		return &Capdu{Cla: c[OffsetCla], Ins: c[OffsetIns], P1: c[OffsetP1], P2: c[OffsetP2], Data: data}, nil
+	if len(body) == LenLCStandard+len(data) {
+		return &Capdu{Cla: cla, Ins: ins, P1: p1, P2: p2, Data: data}, nil
 	}
 
 	// STANDARD CASE 4 command: HEADER | LC | DATA | LE
-	if le := int(c[len(c)-1]); le == 0 {
+	if le := int(body[len(body)-1]); le == 0 {
 		ne = MaxLenResponseDataStandard
 	} else {
 		ne = le
 	}
 
-	return &Capdu{Cla: c[OffsetCla], Ins: c[OffsetIns], P1: c[OffsetP1], P2: c[OffsetP2], Data: data, Ne: ne}, nil
+	return &Capdu{Cla: cla, Ins: ins, P1: p1, P2: p2, Data: data, Ne: ne}, nil
+}
+
+// CapduFromHeaderUint32 returns a Capdu with the header bytes taken from h (CLA<<24 | INS<<16 | P1<<8 | P2) and
+// the given data and ne. It is the counterpart of HeaderUint32. The data is not copied.
+func CapduFromHeaderUint32(h uint32, data []byte, ne int) *Capdu {
+	return &Capdu{Cla: byte(h >> 24), Ins: byte(h >> 16), P1: byte(h >> 8), P2: byte(h), Data: data, Ne: ne}
 }
 
 // ParseCapduHexString decodes the hex-string representation of a Command APDU, calls ParseCapdu and returns a Capdu.
 func ParseCapduHexString(s string) (*Capdu, error) {
+	b, err := decodeHexString(s, "Capdu", LenHeader, MaxLenCapdu)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseCapdu(b)
+}
+
+// ParseCapduBase64 decodes the standard base64 representation of a Command APDU, calls ParseCapdu and returns a Capdu.
+func ParseCapduBase64(s string) (*Capdu, error) {
+	b, err := decodeBase64String(s, MaxLenCapdu)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseCapdu(b)
+}
+
+// decodeBase64String validates that the base64 string s does not exceed the encoded length of maxLen byte and
+// decodes it.
+func decodeBase64String(s string, maxLen int) ([]byte, error) {
+	if len(s) > base64.StdEncoding.EncodedLen(maxLen) {
+		return nil, errors.Errorf("%s: invalid length of base64 string - must not exceed %d characters, got %d", packageTag, base64.StdEncoding.EncodedLen(maxLen), len(s))
+	}
+
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s: base64 conversion error", packageTag)
+	}
+
+	return b, nil
+}
+
+// decodeHexString validates that the hex string s represents between minLen and maxLen byte and decodes it.
+// name is the name of the decoded type used in error messages.
+func decodeHexString(s string, name string, minLen, maxLen int) ([]byte, error) {
 	if len(s)%2 != 0 {
 		return nil, errors.Errorf("%s: uneven number of hex characters", packageTag)
 	}
 
-	if len(s) < 8 || len(s) > 131088 {
-		return nil, errors.Errorf("%s: invalid length of hex string - a Capdu must consist of at least 4 byte and maximum of 65544 byte, got %d", packageTag, len(s)/2)
+	if n := len(s) / 2; n < minLen || n > maxLen {
+		return nil, errors.Errorf("%s: invalid length of hex string - a %s must consist of at least %d byte and maximum of %d byte, got %d", packageTag, name, minLen, maxLen, n)
 	}
 
 	b, err := hex.DecodeString(s)
@@ -167,10 +399,13 @@ func ParseCapduHexString(s string) (*Capdu, error) {
 		return nil, errors.Wrapf(err, "%s: hex conversion error", packageTag)
 	}
 
-	return ParseCapdu(b)
+	return b, nil
 }
 
-// Bytes returns the byte representation of the Capdu.
+// Bytes returns the byte representation of the Capdu. An error is returned if Data exceeds 65535 byte or if Ne is
+// negative or exceeds 65536.
+// The Le field is only emitted if Ne is greater than zero (Case 2 and Case 4), i.e. a Capdu with Data and without Ne
+// is always encoded as Case 3 command consisting of header, Lc and data without a trailing Le.
 func (c *Capdu) Bytes() ([]byte, error) {
 	dataLen := len(c.Data)
 
@@ -179,12 +414,12 @@ func (c *Capdu) Bytes() ([]byte, error) {
 			packageTag, len(c.Data), MaxLenCommandDataExtended)
 	}
 
-	if c.Ne > MaxLenResponseDataExtended {
-		return nil, errors.Errorf("%s: ne %d exceeds maximum allowed length of %d",
-			packageTag, len(c.Data), MaxLenResponseDataExtended)
+	if c.Ne < 0 || c.Ne > MaxLenResponseDataExtended {
+		return nil, errors.Errorf("%s: ne %d must be in the range 0 to %d",
+			packageTag, c.Ne, MaxLenResponseDataExtended)
 	}
 
-	ca := c.determineCase()
+	ca := c.Case()
 
 	switch ca {
 	case 1:
@@ -279,35 +514,540 @@ func (c *Capdu) Bytes() ([]byte, error) {
 	return result, nil
 }
 
-func (c *Capdu) determineCase() int {
-	if len(c.Data) == 0 && c.Ne == 0 {
-		return 1
+// IsCanonicalEncoding returns true if raw equals the result of Bytes, i.e. if raw is the canonical encoding of the
+// Capdu, else false. Non-canonical encodings are e.g. the extended length format for a command that fits into the
+// standard length format or an extended Le of '0100' instead of the standard Le '00' for Ne of 256.
+// An error is returned if the Capdu can't be encoded or if raw is not a valid encoding of the Capdu.
+func (c *Capdu) IsCanonicalEncoding(raw []byte) (bool, error) {
+	b, err := c.Bytes()
+	if err != nil {
+		return false, err
 	}
 
-	if len(c.Data) == 0 && c.Ne > 0 {
-		return 2
+	parsed, err := ParseCapdu(raw)
+	if err != nil {
+		return false, errors.Wrapf(err, "%s: invalid raw encoding", packageTag)
+	}
+
+	if !c.Equal(parsed) {
+		return false, errors.Errorf("%s: raw encoding %X does not encode the Capdu", packageTag, raw)
+	}
+
+	return bytes.Equal(b, raw), nil
+}
+
+// BytesMinimal returns the byte representation of the Capdu with the shortest possible Le field for proprietary readers
+// that treat an absent Le as request for all available response data.
+// BytesMinimal deviates from ISO 7816-4 in exactly one way: if OmitsLeMinimal returns true, the Le field is omitted,
+// i.e. a Case 2 command is encoded as Case 1 and a Case 4 command is encoded as Case 3. Since the Capdu is then encoded
+// without Ne, the format is determined by the length of Data only. In all other cases, BytesMinimal returns the
+// same result as Bytes.
+// Do not use BytesMinimal for readers and cards that comply with ISO 7816-4.
+func (c *Capdu) BytesMinimal() ([]byte, error) {
+	if !c.OmitsLeMinimal() {
+		return c.Bytes()
 	}
 
-	if len(c.Data) != 0 && c.Ne == 0 {
+	minimal := *c
+	minimal.Ne = 0
+
+	return minimal.Bytes()
+}
+
+// OmitsLeMinimal returns true if BytesMinimal omits the Le field of the Capdu, which is the case if Ne requests the
+// maximum number of bytes of the format Bytes would use (256 for standard length, 65536 for extended length),
+// else false.
+func (c *Capdu) OmitsLeMinimal() bool {
+	if c.IsExtendedLength() {
+		return c.Ne == MaxLenResponseDataExtended
+	}
+
+	return c.Ne == MaxLenResponseDataStandard
+}
+
+// Case returns the case of the Capdu according to ISO 7816-4, which determines the fields Bytes encodes:
+//   - 1: no Data and no response data expected (HEADER)
+//   - 2: no Data and response data expected (HEADER | LE)
+//   - 3: Data and no response data expected (HEADER | LC | DATA)
+//   - 4: Data and response data expected (HEADER | LC | DATA | LE)
+//
+// Like ExpectsData, Case considers response data to be expected if Ne is greater than zero. A negative Ne is invalid
+// (see Bytes), Case then only reflects whether Data is present.
+func (c *Capdu) Case() int {
+	hasData := len(c.Data) > 0
+
+	switch {
+	case !hasData && !c.ExpectsData():
+		return 1
+	case !hasData:
+		return 2
+	case !c.ExpectsData():
 		return 3
+	default:
+		return 4
 	}
+}
 
-	return 4
+// AssertCase returns nil if the Capdu is of the expected case (see Case), otherwise an error that states the
+// expected and the actual case, e.g. for conformance tests.
+func (c *Capdu) AssertCase(expected int) error {
+	if actual := c.Case(); actual != expected {
+		return errors.Errorf("%s: expected Case %d command, got Case %d", packageTag, expected, actual)
+	}
+
+	return nil
 }
 
 // String calls Bytes and returns the hex encoded string representation of the Capdu.
+// The command data of sensitive commands is redacted if RedactSensitive is set.
 func (c *Capdu) String() (string, error) {
 	b, err := c.Bytes()
 	if err != nil {
 		return "", err
 	}
 
-	return strings.ToUpper(hex.EncodeToString(b)), nil
+	if !c.redactData() {
+		return strings.ToUpper(hex.EncodeToString(b)), nil
+	}
+
+	offsetData := LenHeader + len(c.LcBytes())
+	offsetLe := offsetData + len(c.Data)
+
+	return strings.ToUpper(hex.EncodeToString(b[:offsetData])) + c.dataString() +
+		strings.ToUpper(hex.EncodeToString(b[offsetLe:])), nil
+}
+
+// HeaderUint32 returns the header of the Capdu as uint32 (CLA<<24 | INS<<16 | P1<<8 | P2).
+func (c *Capdu) HeaderUint32() uint32 {
+	return uint32(c.Cla)<<24 | uint32(c.Ins)<<16 | uint32(c.P1)<<8 | uint32(c.P2)
+}
+
+// HeaderBytes returns the command header of the Capdu (CLA, INS, P1 and P2).
+func (c *Capdu) HeaderBytes() []byte {
+	return []byte{c.Cla, c.Ins, c.P1, c.P2}
+}
+
+// BodyBytes calls Bytes and returns the command body of the Capdu, i.e. everything that follows the header
+// (Lc, Data and Le), which is empty for Case 1 commands. HeaderBytes and BodyBytes are the counterparts of the header
+// and body arguments of ParseCapduParts.
+func (c *Capdu) BodyBytes() ([]byte, error) {
+	b, err := c.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return b[LenHeader:], nil
+}
+
+// ShapeKey returns a key that identifies the structure of the Capdu in the format "CLAINSP1P2/len(Data)/Ne",
+// e.g. "00A40400/7/256". ShapeKey deliberately ignores the content of Data, so commands that differ only in their
+// data of equal length share the same key.
+func (c *Capdu) ShapeKey() string {
+	const hexDigits = "0123456789ABCDEF"
+
+	b := make([]byte, 0, 8+1+5+1+5)
+	for _, v := range []byte{c.Cla, c.Ins, c.P1, c.P2} {
+		b = append(b, hexDigits[v>>4], hexDigits[v&0x0F])
+	}
+
+	b = append(b, '/')
+	b = strconv.AppendInt(b, int64(len(c.Data)), 10)
+	b = append(b, '/')
+	b = strconv.AppendInt(b, int64(c.Ne), 10)
+
+	return string(b)
+}
+
+// GoLiteral calls Bytes and returns the representation of the Capdu as Go byte slice literal,
+// e.g. []byte{0x00, 0xA4, 0x04, 0x00}.
+func (c *Capdu) GoLiteral() (string, error) {
+	b, err := c.Bytes()
+	if err != nil {
+		return "", err
+	}
+
+	return goLiteral(b), nil
+}
+
+// WithNe returns a copy of the Capdu with Ne set to ne, e.g. to turn a Case 1 command into a Case 2 command or a
+// Case 3 command into a Case 4 command. Data is copied and the Capdu is not modified.
+func (c *Capdu) WithNe(ne int) *Capdu {
+	cp := c.clone()
+	cp.Ne = ne
+
+	return cp
+}
+
+// RetryWithLe returns a copy of the Capdu with Ne set to le, which is the command to re-issue after the card indicated
+// a wrong Le field ('6Cxx'). As in SW2 of '6Cxx', an le of 0 denotes 256 bytes, so either SW2 or the result of
+// Rapdu.CorrectLe can be passed. Data is copied and the Capdu is not modified.
+func (c *Capdu) RetryWithLe(le int) *Capdu {
+	if le == 0 {
+		le = MaxLenResponseDataStandard
+	}
+
+	return c.WithNe(le)
+}
+
+// WithInstruction returns a copy of the Capdu with INS set to ins, e.g. to test the behavior of a card for different
+// instructions with the same parameters and data. Data is copied and the Capdu is not modified.
+func (c *Capdu) WithInstruction(ins byte) *Capdu {
+	cp := c.clone()
+	cp.Ins = ins
+
+	return cp
+}
+
+// WithP1P2 returns a copy of the Capdu with P1 and P2 set to p1 and p2. Data is copied and the Capdu is not modified.
+func (c *Capdu) WithP1P2(p1, p2 byte) *Capdu {
+	cp := c.clone()
+	cp.P1 = p1
+	cp.P2 = p2
+
+	return cp
+}
+
+// EnsureLe returns a copy of the Capdu that encodes an Le field. This is a workaround for readers that reject Case 3
+// commands and is not required by ISO 7816-4: a Case 3 command is turned into a Case 4 command with Ne set to the
+// maximum of its length format, i.e. 256 (Le '00') for standard length and 65536 (Le '0000') for extended length.
+// Any other command, including a command with an invalid negative Ne, is returned as unchanged copy. Data is copied
+// and the Capdu is not modified.
+func (c *Capdu) EnsureLe() *Capdu {
+	cp := c.clone()
+
+	if cp.Case() != 3 || cp.Ne < 0 {
+		return cp
+	}
+
+	if cp.IsExtendedLength() {
+		cp.Ne = MaxLenResponseDataExtended
+	} else {
+		cp.Ne = MaxLenResponseDataStandard
+	}
+
+	return cp
+}
+
+// Normalize returns a copy of the Capdu in the canonical form that ParseCapdu returns for its encoding, i.e. empty
+// Data is set to nil. Ne is retained: a negative Ne is invalid and is not corrected, so Bytes still rejects it.
+// Data is copied and the Capdu is not modified.
+func (c *Capdu) Normalize() *Capdu {
+	cp := c.clone()
+
+	if len(cp.Data) == 0 {
+		cp.Data = nil
+	}
+
+	return cp
+}
+
+// clone returns a copy of the Capdu with a copy of Data and Meta.
+func (c *Capdu) clone() *Capdu {
+	cp := *c
+	if c.Data != nil {
+		cp.Data = make([]byte, len(c.Data))
+		copy(cp.Data, c.Data)
+	}
+
+	if c.Meta != nil {
+		cp.Meta = make(map[string]interface{}, len(c.Meta))
+		for k, v := range c.Meta {
+			cp.Meta[k] = v
+		}
+	}
+
+	return &cp
+}
+
+// Equal returns true if the Capdu equals other in all fields, else false. A nil and an empty Data are considered
+// equal.
+func (c *Capdu) Equal(other *Capdu) bool {
+	if c == nil || other == nil {
+		return c == other
+	}
+
+	return c.Cla == other.Cla &&
+		c.Ins == other.Ins &&
+		c.P1 == other.P1 &&
+		c.P2 == other.P2 &&
+		c.Ne == other.Ne &&
+		bytes.Equal(c.Data, other.Data)
+}
+
+// EqualIgnoreChannel returns true if the Capdu equals other in all fields, ignoring the logical channel that is
+// encoded in the class byte, else false. Class bytes of logical channels 0 to 3 and 4 to 19 are considered equal if
+// they indicate the same chaining and secure messaging.
+func (c *Capdu) EqualIgnoreChannel(other *Capdu) bool {
+	if c == nil || other == nil {
+		return c == other
+	}
+
+	a, b := *c, *other
+	a.Cla = classWithoutChannel(a.Cla)
+	b.Cla = classWithoutChannel(b.Cla)
+
+	return a.Equal(&b)
 }
 
-// IsExtendedLength returns true if the Capdu has extended length (len of Data > 65535 or Ne > 65536), else false.
+// Base64 calls Bytes and returns the standard base64 encoded string representation of the Capdu.
+func (c *Capdu) Base64() (string, error) {
+	b, err := c.Bytes()
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// Encoding is the length format Bytes uses to encode a Capdu.
+type Encoding int
+
+const (
+	// EncodingStandard is the format of Capdus with up to 255 byte of data and Ne of up to 256.
+	EncodingStandard Encoding = iota
+	// EncodingExtended is the format of Capdus with more than 255 byte of data or Ne greater than 256.
+	EncodingExtended
+)
+
+// String returns the name of the Encoding, i.e. "standard" or "extended".
+func (e Encoding) String() string {
+	if e == EncodingExtended {
+		return "extended"
+	}
+
+	return "standard"
+}
+
+// Encoding returns the length format Bytes uses to encode the Capdu, which is EncodingExtended if len of Data > 255
+// or Ne > 256, else EncodingStandard.
+func (c *Capdu) Encoding() Encoding {
+	if c.Ne > MaxLenResponseDataStandard || len(c.Data) > MaxLenCommandDataStandard {
+		return EncodingExtended
+	}
+
+	return EncodingStandard
+}
+
+// IsExtendedLength returns true if the Capdu has extended length (len of Data > 255 or Ne > 256), else false.
+// IsExtendedLength is equivalent to comparing the result of Encoding with EncodingExtended.
 func (c *Capdu) IsExtendedLength() bool {
-	return c.Ne > MaxLenResponseDataStandard || len(c.Data) > MaxLenCommandDataStandard
+	return c.Encoding() == EncodingExtended
+}
+
+// IsT0Problematic returns true and an explanation if the Capdu cannot be transmitted as it is with the T=0 protocol,
+// otherwise false and an empty string. This is an advisory check for tools that use a T=0 reader:
+//   - extended length commands must be transmitted with ENVELOPE
+//   - Case 4 commands are transmitted without Le, the response data must be retrieved with GET RESPONSE
+//
+// Case 1, 2 and 3 commands of standard length can be mapped directly to T=0.
+func (c *Capdu) IsT0Problematic() (bool, string) {
+	if c.IsExtendedLength() {
+		return true, "extended length commands are not supported by T=0 and must be transmitted with ENVELOPE"
+	}
+
+	if len(c.Data) > 0 && c.Ne > 0 {
+		return true, "Case 4 commands are transmitted without Le by T=0, response data must be retrieved with GET RESPONSE"
+	}
+
+	return false, ""
+}
+
+// FitsBuffer calls Bytes and returns true if the encoded Capdu does not exceed maxLen byte, else false.
+// This allows to decide whether a command has to be split before it is sent to a reader with a limited buffer.
+func (c *Capdu) FitsBuffer(maxLen int) (bool, error) {
+	b, err := c.Bytes()
+	if err != nil {
+		return false, err
+	}
+
+	return len(b) <= maxLen, nil
+}
+
+// CapduByteDistance calls Bytes for a and b and returns the number of positions at which the encoded Capdus differ.
+// If the encodings differ in length, each byte of the longer encoding that exceeds the shorter one counts as a
+// difference. An error is returned if a or b can't be encoded.
+func CapduByteDistance(a, b *Capdu) (int, error) {
+	ab, err := a.Bytes()
+	if err != nil {
+		return 0, errors.Wrapf(err, "%s: encode first Capdu", packageTag)
+	}
+
+	bb, err := b.Bytes()
+	if err != nil {
+		return 0, errors.Wrapf(err, "%s: encode second Capdu", packageTag)
+	}
+
+	if len(ab) < len(bb) {
+		ab, bb = bb, ab
+	}
+
+	distance := len(ab) - len(bb)
+
+	for i := range bb {
+		if ab[i] != bb[i] {
+			distance++
+		}
+	}
+
+	return distance, nil
+}
+
+// ExtendedLengthStats returns the number of Capdus in cmds and the number of those that require extended length
+// according to IsExtendedLength. Nil entries are not counted.
+func ExtendedLengthStats(cmds []*Capdu) (total, extended int) {
+	for _, c := range cmds {
+		if c == nil {
+			continue
+		}
+
+		total++
+
+		if c.IsExtendedLength() {
+			extended++
+		}
+	}
+
+	return total, extended
+}
+
+// MaxResponseBytes returns the maximum number of response data bytes the Capdu requests, which is 0 if no response
+// data is expected. Since Ne is not encoded as Le, the maximum values 256 (Le '00') and 65536 (Le '0000') are
+// returned as they are. A negative Ne is invalid and can't be encoded by Bytes, so no response can be provoked and 0
+// is returned.
+func (c *Capdu) MaxResponseBytes() int {
+	if c.Ne < 0 {
+		return 0
+	}
+
+	return c.Ne
+}
+
+// DescribeNe returns a human readable description of ne and the Le that encodes it, e.g.
+// "expect up to 256 bytes (standard, Le=00)" or "expect up to 65536 bytes (extended, Le=0000)".
+// "no response expected" is returned if ne is zero and "invalid Ne" if ne is negative.
+func DescribeNe(ne int) string {
+	switch {
+	case ne < 0:
+		return fmt.Sprintf("invalid Ne %d", ne)
+	case ne == 0:
+		return "no response expected"
+	case ne <= MaxLenResponseDataStandard:
+		return fmt.Sprintf("expect up to %d bytes (standard, Le=%02X)", ne, byte(ne))
+	case ne <= MaxLenResponseDataExtended:
+		return fmt.Sprintf("expect up to %d bytes (extended, Le=%04X)", ne, uint16(ne))
+	default:
+		return fmt.Sprintf("invalid Ne %d (exceeds maximum of %d)", ne, MaxLenResponseDataExtended)
+	}
+}
+
+// MaxResponseFrameSize returns the maximum length of the RAPDU the Capdu can provoke, i.e. MaxResponseBytes plus the
+// length of the trailer, capped at MaxLenRapdu. This is the size of a receive buffer that can hold any valid response
+// to the Capdu. If no response data is expected, only the length of the trailer is returned.
+func (c *Capdu) MaxResponseFrameSize() int {
+	size := c.MaxResponseBytes() + LenResponseTrailer
+	if size > MaxLenRapdu {
+		return MaxLenRapdu
+	}
+
+	return size
+}
+
+// ExpectsData returns true if the Capdu expects response data, i.e. Ne is greater than zero (Case 2 and Case 4),
+// else false (Case 1 and Case 3). Bytes encodes an Le field exactly if ExpectsData returns true.
+func (c *Capdu) ExpectsData() bool {
+	return c.Ne > 0
+}
+
+// EmitsLe returns true if Bytes encodes an Le field for the Capdu, which is the case if Ne is greater than zero, else false.
+//
+// Deprecated: Use ExpectsData, which is equivalent.
+func (c *Capdu) EmitsLe() bool {
+	return c.ExpectsData()
+}
+
+// LcBytes returns the Lc field Bytes encodes for the Capdu, which is nil if the Capdu contains no data, one byte for
+// the standard length format and three bytes (a zero byte followed by the length of Data) for the extended length
+// format.
+func (c *Capdu) LcBytes() []byte {
+	dataLen := len(c.Data)
+
+	if dataLen == 0 {
+		return nil
+	}
+
+	if c.IsExtendedLength() {
+		return []byte{0x00, (byte)((dataLen >> 8) & 0xFF), (byte)(dataLen & 0xFF)}
+	}
+
+	return []byte{byte(dataLen)}
+}
+
+// LeBytes calls Bytes and returns the Le field of the encoded Capdu, which is nil for Case 1 and Case 3 commands,
+// one byte for the standard length format, three bytes for an extended Case 2 and two bytes for an extended Case 4
+// command. The maximum values of Ne are encoded as '00' (256) and '0000' (65536) respectively.
+func (c *Capdu) LeBytes() ([]byte, error) {
+	b, err := c.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	var lenLe int
+
+	switch c.Case() {
+	case 1, 3:
+		return nil, nil
+	case 2:
+		lenLe = 1
+		if c.IsExtendedLength() {
+			lenLe = LenLCExtended // first byte is zero byte, so LE length is equal to LC length
+		}
+	default:
+		lenLe = 1
+		if c.IsExtendedLength() {
+			lenLe = 2
+		}
+	}
+
+	le := make([]byte, lenLe)
+	copy(le, b[len(b)-lenLe:])
+
+	return le, nil
+}
+
+// SFI returns the short EF identifier that is encoded in bits 8 to 4 of P2 and true, if the Capdu is an interindustry
+// record command that references an EF by SFI. The recognized instructions are ERASE RECORD(S) ('0C'),
+// SEARCH RECORD ('A2'), READ RECORD(S) ('B2' and 'B3'), WRITE RECORD ('D2'), UPDATE RECORD ('DC' and 'DD')
+// and APPEND RECORD ('E2').
+// SFI returns false for any other instruction, for proprietary, RFU and invalid classes and if P2 references the
+// currently selected EF (SFI 0) or contains the RFU value 31.
+func (c *Capdu) SFI() (byte, bool) {
+	if isProprietaryClass(c.Cla) || !isValidClass(c.Cla) {
+		return 0, false
+	}
+
+	switch c.Ins {
+	case 0x0C, 0xA2, 0xB2, 0xB3, 0xD2, 0xDC, 0xDD, 0xE2:
+	default:
+		return 0, false
+	}
+
+	sfi := c.P2 >> 3
+	if sfi == 0 || sfi == 0x1F {
+		return 0, false
+	}
+
+	return sfi, true
+}
+
+// SelectedAID returns the Data of the Capdu and true, if the Capdu is an interindustry SELECT by DF name (P1 '04'),
+// which is used to select applications by AID. SelectedAID returns false for any other command, for the other
+// selection modes (e.g. by file identifier or path) and if the Capdu does not contain data.
+func (c *Capdu) SelectedAID() ([]byte, bool) {
+	if isProprietaryClass(c.Cla) || c.Ins != InsSelect || c.P1 != 0x04 || len(c.Data) == 0 {
+		return nil, false
+	}
+
+	return c.Data, true
 }
 
 // Rapdu is a Response APDU.
@@ -317,10 +1057,28 @@ type Rapdu struct {
 	SW2  byte   // SW2 is the second byte of a status word.
 }
 
+// NewRapdu returns a Rapdu with a copy of data and the status word sw1 sw2. This allows to assemble a Rapdu if response
+// data and status word are delivered separately. Data of the returned Rapdu is nil if data is empty.
+func NewRapdu(data []byte, sw1, sw2 byte) *Rapdu {
+	r := &Rapdu{SW1: sw1, SW2: sw2}
+
+	if len(data) > 0 {
+		r.Data = make([]byte, len(data))
+		copy(r.Data, data)
+	}
+
+	return r
+}
+
+// NewRapduFromParts calls NewRapdu with the status word sw split into SW1 and SW2.
+func NewRapduFromParts(data []byte, sw uint16) *Rapdu {
+	return NewRapdu(data, byte(sw>>8), byte(sw))
+}
+
 // ParseRapdu parses a Response APDU and returns a Rapdu.
 func ParseRapdu(b []byte) (*Rapdu, error) {
-	if len(b) < LenResponseTrailer || len(b) > 65538 {
-		return nil, errors.Errorf("%s: invalid length - a RAPDU must consist of at least 2 byte and maximum of 65538 byte, got %d", packageTag, len(b))
+	if len(b) < LenResponseTrailer || len(b) > MaxLenRapdu {
+		return nil, errors.Errorf("%s: invalid length - a RAPDU must consist of at least %d byte and maximum of %d byte, got %d", packageTag, LenResponseTrailer, MaxLenRapdu, len(b))
 	}
 
 	if len(b) == LenResponseTrailer {
@@ -330,25 +1088,55 @@ func ParseRapdu(b []byte) (*Rapdu, error) {
 	return &Rapdu{Data: b[:len(b)-LenResponseTrailer], SW1: b[len(b)-2], SW2: b[len(b)-1]}, nil
 }
 
+// ParseRapduChecked calls ParseRapdu and returns the Rapdu together with true if the Rapdu contains response data
+// although its status word indicates an error (see IsError), else false. Cards are not supposed to return data
+// with an error, but some return diagnostic data, which is worth flagging.
+func ParseRapduChecked(b []byte) (*Rapdu, bool, error) {
+	r, err := ParseRapdu(b)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return r, len(r.Data) > 0 && r.IsError(), nil
+}
+
+// ParseRapduSWFirst parses a Response APDU of a non-compliant device that puts the status word in front of the
+// response data and returns a Rapdu. This is a workaround: ParseRapdu, which expects the status word to follow the
+// response data as defined in ISO 7816-4, is the correct choice for all other devices.
+func ParseRapduSWFirst(b []byte) (*Rapdu, error) {
+	if len(b) < LenResponseTrailer || len(b) > MaxLenRapdu {
+		return nil, errors.Errorf("%s: invalid length - a RAPDU must consist of at least %d byte and maximum of %d byte, got %d", packageTag, LenResponseTrailer, MaxLenRapdu, len(b))
+	}
+
+	if len(b) == LenResponseTrailer {
+		return &Rapdu{SW1: b[0], SW2: b[1]}, nil
+	}
+
+	return &Rapdu{Data: b[LenResponseTrailer:], SW1: b[0], SW2: b[1]}, nil
+}
+
 // ParseRapduHexString decodes the hex-string representation of a Response APDU, calls ParseRapdu and returns a Rapdu.
 func ParseRapduHexString(s string) (*Rapdu, error) {
-	if len(s)%2 != 0 {
-		return nil, errors.Errorf("%s: uneven number of hex characters", packageTag)
+	b, err := decodeHexString(s, "RAPDU", LenResponseTrailer, MaxLenRapdu)
+	if err != nil {
+		return nil, err
 	}
 
-	if len(s) < 4 || len(s) > 131076 {
-		return nil, errors.Errorf("%s: invalid length of hex string - a RAPDU must consist of at least 2 byte and maximum of 65538 byte, got %d", packageTag, len(s)/2)
-	}
+	return ParseRapdu(b)
+}
 
-	tmp, err := hex.DecodeString(s)
+// ParseRapduBase64 decodes the standard base64 representation of a Response APDU, calls ParseRapdu and returns a Rapdu.
+func ParseRapduBase64(s string) (*Rapdu, error) {
+	b, err := decodeBase64String(s, MaxLenRapdu)
 	if err != nil {
-		return nil, errors.Wrapf(err, "%s: hex conversion error", packageTag)
+		return nil, err
 	}
 
-	return ParseRapdu(tmp)
+	return ParseRapdu(b)
 }
 
 // Bytes returns the byte representation of the RAPDU.
+// Data is never truncated - an error is returned if the length of Data exceeds 65536 byte.
 func (r *Rapdu) Bytes() ([]byte, error) {
 	if len(r.Data) > MaxLenResponseDataExtended {
 		return nil, errors.Errorf("%s: len of Rapdu.Data %d exceeds maximum allowed length of %d",
@@ -372,17 +1160,274 @@ func (r *Rapdu) String() (string, error) {
 	return strings.ToUpper(hex.EncodeToString(b)), nil
 }
 
+// TruncateToNe returns a copy of the RAPDU with Data truncated to ne byte, which protects callers against cards that
+// return more data than requested. An ne of 0 (or less) means that no data was expected, so the copy contains no
+// data. An ne exceeding MaxLenResponseDataExtended is limited to that value. The status word is retained and the
+// RAPDU is not modified.
+func (r *Rapdu) TruncateToNe(ne int) *Rapdu {
+	if ne < 0 {
+		ne = 0
+	}
+
+	if ne > MaxLenResponseDataExtended {
+		ne = MaxLenResponseDataExtended
+	}
+
+	data := r.Data
+	if len(data) > ne {
+		data = data[:ne]
+	}
+
+	truncated := &Rapdu{SW1: r.SW1, SW2: r.SW2}
+	if len(data) > 0 {
+		truncated.Data = append([]byte(nil), data...)
+	}
+
+	return truncated
+}
+
+// GoLiteral calls Bytes and returns the representation of the RAPDU as Go byte slice literal, e.g. []byte{0x90, 0x00}.
+func (r *Rapdu) GoLiteral() (string, error) {
+	b, err := r.Bytes()
+	if err != nil {
+		return "", err
+	}
+
+	return goLiteral(b), nil
+}
+
+// goLiteral returns the representation of b as Go byte slice literal.
+func goLiteral(b []byte) string {
+	sb := strings.Builder{}
+	sb.Grow(len("[]byte{}") + len(b)*len("0x00, "))
+	sb.WriteString("[]byte{")
+
+	for i, v := range b {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+
+		sb.WriteString("0x")
+		sb.WriteString(strings.ToUpper(hex.EncodeToString([]byte{v})))
+	}
+
+	sb.WriteString("}")
+
+	return sb.String()
+}
+
+// DataEqualsMasked returns true if the Data of the RAPDU equals expected in all bytes for which the corresponding byte
+// of mask is 0xFF, otherwise false. Bytes for which the mask is not 0xFF are ignored.
+// DataEqualsMasked returns false if the lengths of Data, expected and mask differ.
+func (r *Rapdu) DataEqualsMasked(expected, mask []byte) bool {
+	if len(r.Data) != len(expected) || len(expected) != len(mask) {
+		return false
+	}
+
+	for i := range mask {
+		if mask[i] == 0xFF && r.Data[i] != expected[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// LooksLikeTLV returns true if Data plausibly starts with a BER-TLV data object, else false. This is a fast heuristic
+// that only checks the outer tag and length field: the first byte must be neither '00' nor 'FF' (which are used for
+// padding), tag and length field must be well-formed and the declared length must not exceed the remaining data.
+// The value and any subsequent data objects are not parsed, so a complete parse may still fail.
+func (r *Rapdu) LooksLikeTLV() bool {
+	if len(r.Data) == 0 || r.Data[0] == 0x00 || r.Data[0] == 0xFF {
+		return false
+	}
+
+	t, n, err := parseTLVHeader(r.Data)
+	if err != nil {
+		return false
+	}
+
+	return t.length <= len(r.Data)-n
+}
+
+// IsTruncatedTLV parses the tag and length field of the outer BER-TLV data object of Data and returns true if the
+// length field declares more bytes than Data actually contains after tag and length field, which indicates that the
+// response data is incomplete. An error is returned if tag or length field of the outer data object are malformed.
+func (r *Rapdu) IsTruncatedTLV() (bool, error) {
+	t, n, err := parseTLVHeader(r.Data)
+	if err != nil {
+		return false, errors.Wrapf(err, "%s: invalid outer TLV", packageTag)
+	}
+
+	return t.length > len(r.Data)-n, nil
+}
+
+// Base64 calls Bytes and returns the standard base64 encoded string representation of the RAPDU.
+func (r *Rapdu) Base64() (string, error) {
+	b, err := r.Bytes()
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// IsValidStatusWord returns true if SW1 is structurally valid according to ISO 7816-4, otherwise false.
+// A valid SW1 is either '6X' with X not equal to '0' or '9X'. SW1 '60' is never a status word since it is the
+// NULL procedure byte of T=0, and any SW1 outside of '6X' and '9X' indicates that the last two bytes of a response
+// are not a status word at all.
+func (r *Rapdu) IsValidStatusWord() bool {
+	switch r.SW1 & 0xF0 {
+	case 0x60:
+		return r.SW1 != 0x60
+	case 0x90:
+		return true
+	default:
+		return false
+	}
+}
+
 // IsSuccess returns true if the RAPDU indicates the successful execution of a command ('0x61xx' or '0x9000'), otherwise false.
 func (r *Rapdu) IsSuccess() bool {
 	return r.SW1 == 0x61 || r.SW1 == 0x90 && r.SW2 == 0x00
 }
 
+// IsSuccessWith returns true if the RAPDU indicates the successful execution of a command according to IsSuccess or
+// if the status word equals one of the given status words, otherwise false.
+func (r *Rapdu) IsSuccessWith(extra ...uint16) bool {
+	if r.IsSuccess() {
+		return true
+	}
+
+	sw := r.sw()
+	for _, e := range extra {
+		if sw == e {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sw returns the status word as combination of SW1 and SW2.
+func (r *Rapdu) sw() uint16 {
+	return uint16(r.SW1)<<8 | uint16(r.SW2)
+}
+
+// Split returns the response data and the status word as combination of SW1 and SW2. The data is returned as it is,
+// i.e. nil data is not converted to an empty slice and the returned slice shares the underlying array with r.Data.
+func (r *Rapdu) Split() ([]byte, uint16) {
+	return r.Data, r.sw()
+}
+
 // IsWarning returns true if the RAPDU indicates the execution of a command with a warning ('0x62xx' or '0x63xx'), otherwise false.
 func (r *Rapdu) IsWarning() bool {
 	return r.SW1 == 0x62 || r.SW1 == 0x63
 }
 
+// StateChanged reports whether the state of the non-volatile memory has been changed by a command that completed with a
+// warning. changed is true for '0x63xx' and false for '0x62xx'. known is false if the RAPDU does not indicate a
+// warning, in which case changed is always false.
+func (r *Rapdu) StateChanged() (changed bool, known bool) {
+	switch r.SW1 {
+	case 0x62:
+		return false, true
+	case 0x63:
+		return true, true
+	default:
+		return false, false
+	}
+}
+
 // IsError returns true if the RAPDU indicates an error during the execution of a command ('0x64xx', '0x65xx' or from '0x67xx' to 0x6Fxx'), otherwise false.
 func (r *Rapdu) IsError() bool {
 	return (r.SW1 == 0x64 || r.SW1 == 0x65) || (r.SW1 >= 0x67 && r.SW1 <= 0x6F)
 }
+
+// HasMoreData returns true if the RAPDU indicates that more response data is available and can be retrieved with
+// GET RESPONSE ('0x61xx'), otherwise false.
+func (r *Rapdu) HasMoreData() bool {
+	return r.SW1 == 0x61
+}
+
+// CorrectLe returns the exact number of available response data bytes and true if the RAPDU indicates a wrong Le
+// field ('0x6Cxx'), otherwise 0 and false. A SW2 of 0x00 indicates 256 available bytes.
+func (r *Rapdu) CorrectLe() (int, bool) {
+	if r.SW1 != 0x6C {
+		return 0, false
+	}
+
+	if r.SW2 == 0x00 {
+		return MaxLenResponseDataStandard, true
+	}
+
+	return int(r.SW2), true
+}
+
+// IsTerminal returns true if the status word of the RAPDU requires no follow-up command, i.e. neither HasMoreData
+// ('0x61xx') nor CorrectLe ('0x6Cxx') apply, otherwise false. IsTerminal can be used as exit condition when sending
+// GET RESPONSE or repeating a command with the correct Le in a loop.
+func (r *Rapdu) IsTerminal() bool {
+	_, wrongLe := r.CorrectLe()
+
+	return !r.HasMoreData() && !wrongLe
+}
+
+// ConcatData returns the concatenation of the Data of the given RAPDUs in the given order, e.g. to reassemble the
+// response data of several GET RESPONSE or READ BINARY commands. The status words are ignored, as are nil RAPDUs.
+// The returned slice is never nil, so an empty, non-nil slice is returned if there is no data at all.
+func ConcatData(rapdus ...*Rapdu) []byte {
+	n := 0
+
+	for _, r := range rapdus {
+		if r != nil {
+			n += len(r.Data)
+		}
+	}
+
+	data := make([]byte, 0, n)
+
+	for _, r := range rapdus {
+		if r != nil {
+			data = append(data, r.Data...)
+		}
+	}
+
+	return data
+}
+
+// IsCompleteExchange returns true if the Rapdu is a complete and final response to the Capdu, otherwise false.
+// The following heuristics are applied:
+//   - the status word must not request a follow-up command, see IsTerminal
+//   - the length of the response data must not exceed Ne of the Capdu, which means that no response data is allowed
+//     for Case 1 and Case 3 commands.
+func IsCompleteExchange(c *Capdu, r *Rapdu) bool {
+	if !r.IsTerminal() {
+		return false
+	}
+
+	return len(r.Data) <= c.Ne
+}
+
+// ConsistentExchange returns true if the lengths of the Capdu and the Rapdu are consistent, otherwise false and the
+// reason of the inconsistency. This is an advisory heuristic e.g. for captured exchanges, since cards do not always
+// adhere to ISO 7816-4:
+//   - Case 1 and Case 3 commands (Ne is 0) should not result in response data
+//   - Case 2 and Case 4 commands should not result in more than Ne bytes of response data
+//
+// An invalid Ne (negative or greater than 65536) is reported as inconsistency as well.
+func ConsistentExchange(c *Capdu, r *Rapdu) (bool, string) {
+	if c.Ne < 0 || c.Ne > MaxLenResponseDataExtended {
+		return false, fmt.Sprintf("command has invalid Ne %d - must be in the range 0 to %d", c.Ne, MaxLenResponseDataExtended)
+	}
+
+	if c.Ne == 0 && len(r.Data) > 0 {
+		return false, fmt.Sprintf("response contains %d byte of data although the command expects none", len(r.Data))
+	}
+
+	if len(r.Data) > c.Ne {
+		return false, fmt.Sprintf("response contains %d byte of data although the command expects at most %d byte", len(r.Data), c.Ne)
+	}
+
+	return true, ""
+}
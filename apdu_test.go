@@ -1,7 +1,11 @@
 package apdu
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -40,6 +44,24 @@ func TestParseCapdu(t *testing.T) {
 			want:    nil,
 			wantErr: true,
 		},
+		{
+			name:    "error: extended length LC zero with trailing LE",
+			args:    args{[]byte{0x00, 0xA4, 0x04, 0x01, 0x00, 0x00, 0x00, 0x01, 0x00}},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: extended length LC zero with data",
+			args:    args{[]byte{0x00, 0xA4, 0x04, 0x01, 0x00, 0x00, 0x00, 0x01}},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: extended length body too short",
+			args:    args{[]byte{0x00, 0xA4, 0x04, 0x01, 0x00, 0x01}},
+			want:    nil,
+			wantErr: true,
+		},
 		{
 			name:    "Case 1",
 			args:    args{[]byte{0x00, 0xA4, 0x04, 0x00}},
@@ -122,366 +144,3019 @@ func TestParseCapdu(t *testing.T) {
 	}
 }
 
-func TestParseCapduHexString(t *testing.T) {
-	type args struct {
-		s string
+func TestParseCapdu_ExtendedLcBoundaries(t *testing.T) {
+	// extendedCapdu returns a Capdu with the given extended Lc that is followed by n data bytes and the given trailer
+	extendedCapdu := func(lc int, n int, trailer ...byte) []byte {
+		b := []byte{0x00, 0xDA, 0x01, 0x02, 0x00, byte(lc >> 8), byte(lc)}
+		b = append(b, make([]byte, n)...)
+
+		return append(b, trailer...)
 	}
 
 	tests := []struct {
 		name    string
-		args    args
-		want    *Capdu
+		c       []byte
+		wantLen int
+		wantNe  int
 		wantErr bool
 	}{
 		{
-			name:    "error: uneven number bytes",
-			args:    args{s: "000102030"},
-			want:    nil,
+			name:    "Case 3 Lc 65533",
+			c:       extendedCapdu(65533, 65533),
+			wantLen: 65533,
+			wantNe:  0,
+			wantErr: false,
+		},
+		{
+			name:    "Case 3 Lc 65534",
+			c:       extendedCapdu(65534, 65534),
+			wantLen: 65534,
+			wantNe:  0,
+			wantErr: false,
+		},
+		{
+			name:    "Case 3 Lc 65535",
+			c:       extendedCapdu(65535, 65535),
+			wantLen: 65535,
+			wantNe:  0,
+			wantErr: false,
+		},
+		{
+			name:    "Case 4 Lc 65533",
+			c:       extendedCapdu(65533, 65533, 0x01, 0x00),
+			wantLen: 65533,
+			wantNe:  256,
+			wantErr: false,
+		},
+		{
+			name:    "Case 4 Lc 65534",
+			c:       extendedCapdu(65534, 65534, 0xFF, 0xFF),
+			wantLen: 65534,
+			wantNe:  65535,
+			wantErr: false,
+		},
+		{
+			name:    "Case 4 Lc 65535",
+			c:       extendedCapdu(65535, 65535, 0x00, 0x00),
+			wantLen: 65535,
+			wantNe:  65536,
+			wantErr: false,
+		},
+		{
+			name:    "error: Lc 65533 with one additional byte",
+			c:       extendedCapdu(65533, 65534),
 			wantErr: true,
 		},
 		{
-			name:    "error: invalid length",
-			args:    args{s: "000102"},
-			want:    nil,
+			name:    "error: Lc 65533 with three additional bytes",
+			c:       extendedCapdu(65533, 65536),
 			wantErr: true,
 		},
 		{
-			name:    "error: invalid characters",
-			args:    args{"s:00010203GG"},
-			want:    nil,
+			name:    "error: Lc 65535 with one byte missing",
+			c:       extendedCapdu(65535, 65534),
 			wantErr: true,
 		},
 		{
-			name:    "standard length CASE 1",
-			args:    args{s: "00A40401"},
-			want:    &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Ne: 0},
-			wantErr: false,
+			name:    "error: Lc 65535 with one byte Le",
+			c:       extendedCapdu(65535, 65535, 0x00),
+			wantErr: true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := ParseCapduHexString(tt.args.s)
+			got, err := ParseCapdu(tt.c)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("ParseCapduHexString() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("ParseCapdu() error = %v, wantErr %v", err, tt.wantErr)
 
 				return
 			}
-			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("ParseCapduHexString() got = %v, want %v", got, tt.want)
+
+			if tt.wantErr {
+				return
+			}
+
+			if len(got.Data) != tt.wantLen {
+				t.Errorf("ParseCapdu() got len(Data) = %d, want %d", len(got.Data), tt.wantLen)
+			}
+
+			if got.Ne != tt.wantNe {
+				t.Errorf("ParseCapdu() got Ne = %d, want %d", got.Ne, tt.wantNe)
 			}
 		})
 	}
 }
 
-func TestParseRapdu(t *testing.T) {
-	type args struct {
-		b []byte
+func TestParseCapdu_MaxExtendedLcData(t *testing.T) {
+	// patterned data reveals an offset of the Data slice, which data of zero bytes would not
+	data := make([]byte, MaxLenCommandDataExtended)
+	for i := range data {
+		data[i] = byte(i % 251)
 	}
 
+	header := []byte{0x00, 0xDA, 0x01, 0x02, 0x00, 0xFF, 0xFF}
+
 	tests := []struct {
-		name    string
-		args    args
-		want    *Rapdu
-		wantErr bool
+		name   string
+		c      []byte
+		wantNe int
 	}{
 		{
-			name:    "error: invalid length too small",
-			args:    args{b: []byte{0x6A}},
-			want:    nil,
-			wantErr: true,
-		},
-		{
-			name:    "error: invalid length too big",
-			args:    args{b: make([]byte, 65539)},
-			want:    nil,
-			wantErr: true,
-		},
-		{
-			name:    "only SW",
-			args:    args{b: []byte{0x6A, 0x80}},
-			want:    &Rapdu{Data: nil, SW1: 0x6A, SW2: 0x80},
-			wantErr: false,
+			name:   "Case 3",
+			c:      append(append([]byte{}, header...), data...),
+			wantNe: 0,
 		},
 		{
-			name:    "data and SW",
-			args:    args{b: []byte{0x01, 0x02, 0x03, 0x90, 0x00}},
-			want:    &Rapdu{Data: []byte{0x01, 0x02, 0x03}, SW1: 0x90, SW2: 0x00},
-			wantErr: false,
+			name:   "Case 4",
+			c:      append(append(append([]byte{}, header...), data...), 0x00, 0x00),
+			wantNe: 65536,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := ParseRapdu(tt.args.b)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("ParseRapdu() error = %v, wantErr %v", err, tt.wantErr)
+			got, err := ParseCapdu(tt.c)
+			if err != nil {
+				t.Fatalf("ParseCapdu() error = %v", err)
+			}
 
-				return
+			if len(got.Data) != MaxLenCommandDataExtended {
+				t.Errorf("ParseCapdu() got len(Data) = %d, want %d", len(got.Data), MaxLenCommandDataExtended)
 			}
-			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("ParseRapdu() got = %v, want %v", got, tt.want)
+
+			if !bytes.Equal(got.Data, data) {
+				t.Errorf("ParseCapdu() got Data that differs from the encoded data")
+			}
+
+			if got.Ne != tt.wantNe {
+				t.Errorf("ParseCapdu() got Ne = %d, want %d", got.Ne, tt.wantNe)
+			}
+
+			b, err := got.Bytes()
+			if err != nil {
+				t.Fatalf("Bytes() error = %v", err)
+			}
+
+			if !bytes.Equal(b, tt.c) {
+				t.Errorf("Bytes() does not reproduce the parsed Capdu")
 			}
 		})
 	}
 }
 
-func TestParseRapduHexString(t *testing.T) {
-	type args struct {
-		s string
-	}
-
+func TestParseCapdu_LcMismatchError(t *testing.T) {
 	tests := []struct {
 		name    string
-		args    args
-		want    *Rapdu
-		wantErr bool
+		c       []byte
+		wantErr string
 	}{
 		{
-			name:    "error: uneven number bytes",
-			args:    args{s: "6A80A"},
-			want:    nil,
-			wantErr: true,
+			name:    "standard Lc exceeds body",
+			c:       []byte{0x00, 0xD6, 0x00, 0x00, 0x05, 0x01, 0x02},
+			wantErr: "Lc indicates length 5, but 2 byte available after Lc",
 		},
 		{
-			name:    "error: invalid length",
-			args:    args{s: "6A"},
-			want:    nil,
-			wantErr: true,
+			name:    "standard Lc too short for body",
+			c:       []byte{0x00, 0xD6, 0x00, 0x00, 0x01, 0x01, 0x02, 0x03, 0x04},
+			wantErr: "Lc indicates length 1, but 4 byte available after Lc",
 		},
 		{
-			name:    "error: invalid characters",
-			args:    args{s: "FFGF6A88"},
-			want:    nil,
-			wantErr: true,
+			name:    "extended Lc exceeds body",
+			c:       []byte{0x00, 0xD6, 0x00, 0x00, 0x00, 0x01, 0x00, 0x01, 0x02},
+			wantErr: "LC indicates data length 256, but 2 byte available after LC",
 		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseCapdu(tt.c)
+			if err == nil {
+				t.Fatalf("ParseCapdu() error = nil, want error containing %q", tt.wantErr)
+			}
+
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseCapdu() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseCapduWithRaw(t *testing.T) {
+	tests := []struct {
+		name    string
+		c       []byte
+		want    *Capdu
+		wantRaw []byte
+		wantErr bool
+	}{
 		{
-			name:    "only SW",
-			args:    args{s: "6A80"},
-			want:    &Rapdu{Data: nil, SW1: 0x6A, SW2: 0x80},
+			name:    "canonical",
+			c:       []byte{0x00, 0xA4, 0x04, 0x00, 0x02, 0x01, 0x02, 0x00},
+			want:    &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0x01, 0x02}, Ne: 256},
+			wantRaw: []byte{0x00, 0xA4, 0x04, 0x00, 0x02, 0x01, 0x02, 0x00},
 			wantErr: false,
 		},
 		{
-			name:    "data and SW",
-			args:    args{s: "0102039000"},
-			want:    &Rapdu{Data: []byte{0x01, 0x02, 0x03}, SW1: 0x90, SW2: 0x00},
+			name:    "non-canonical extended encoding",
+			c:       []byte{0x00, 0xA4, 0x04, 0x00, 0x00, 0x00, 0x02, 0x01, 0x02, 0x01, 0x00},
+			want:    &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0x01, 0x02}, Ne: 256},
+			wantRaw: []byte{0x00, 0xA4, 0x04, 0x00, 0x00, 0x00, 0x02, 0x01, 0x02, 0x01, 0x00},
 			wantErr: false,
 		},
+		{
+			name:    "error: invalid length",
+			c:       []byte{0x00, 0xA4, 0x04},
+			want:    nil,
+			wantRaw: nil,
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := ParseRapduHexString(tt.args.s)
+			got, gotRaw, err := ParseCapduWithRaw(tt.c)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("ParseRapduHexString() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("ParseCapduWithRaw() error = %v, wantErr %v", err, tt.wantErr)
 
 				return
 			}
 			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("ParseRapduHexString() got = %v, want %v", got, tt.want)
+				t.Errorf("ParseCapduWithRaw() got = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(gotRaw, tt.wantRaw) {
+				t.Errorf("ParseCapduWithRaw() gotRaw = %X, want %X", gotRaw, tt.wantRaw)
 			}
-		})
-	}
-}
-
-func TestCapdu_Bytes(t *testing.T) {
-	extendedData := make([]byte, 65535)
-	for i := range extendedData {
-		extendedData[i] = 0xFF
-	}
 
-	tooExtendedData := make([]byte, 65536)
+			if tt.wantErr {
+				return
+			}
 
-	type fields struct {
-		Cla  byte
-		Ins  byte
-		P1   byte
-		P2   byte
-		Data []byte
-		Ne   int
+			// modifying the input must not affect the copy
+			tt.c[0] = 0xFF
+			if gotRaw[0] == 0xFF {
+				t.Errorf("ParseCapduWithRaw() raw is not a copy")
+			}
+		})
 	}
+}
 
+func TestParseCapduWithLengthFormat(t *testing.T) {
 	tests := []struct {
-		name    string
-		fields  fields
-		want    []byte
-		wantErr bool
+		name         string
+		c            []byte
+		want         *Capdu
+		wantExtended bool
+		wantErr      bool
 	}{
 		{
-			name:    "standard length CASE 1",
-			fields:  fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Ne: 0},
-			want:    []byte{0x00, 0xA4, 0x04, 0x01},
-			wantErr: false,
+			name:         "Case 1",
+			c:            []byte{0x00, 0x70, 0x00, 0x00},
+			want:         &Capdu{Cla: 0x00, Ins: 0x70, P1: 0x00, P2: 0x00},
+			wantExtended: false,
+			wantErr:      false,
 		},
 		{
-			name:    "standard length CASE 2 LE unequal zero",
-			fields:  fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Ne: 255},
-			want:    []byte{0x00, 0xA4, 0x04, 0x01, 0xFF},
-			wantErr: false,
+			name:         "standard Case 2 with Le 00",
+			c:            []byte{0x00, 0xB0, 0x00, 0x00, 0x00},
+			want:         &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 256},
+			wantExtended: false,
+			wantErr:      false,
 		},
 		{
-			name:    "standard length CASE 2 LE equal zero",
-			fields:  fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Ne: 256},
-			want:    []byte{0x00, 0xA4, 0x04, 0x01, 0x00},
-			wantErr: false,
+			name:         "small Case 2 encoded extended",
+			c:            []byte{0x00, 0xB0, 0x00, 0x00, 0x00, 0x00, 0x10},
+			want:         &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 16},
+			wantExtended: true,
+			wantErr:      false,
 		},
 		{
-			name:    "standard length CASE 3",
-			fields:  fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Data: []byte{0x01, 0x02, 0x3}, Ne: 0},
-			want:    []byte{0x00, 0xA4, 0x04, 0x01, 0x03, 0x01, 0x02, 0x03},
-			wantErr: false,
+			name:         "standard Case 4",
+			c:            []byte{0x00, 0xA4, 0x04, 0x00, 0x02, 0xA0, 0x00, 0x00},
+			want:         &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0xA0, 0x00}, Ne: 256},
+			wantExtended: false,
+			wantErr:      false,
 		},
 		{
-			name:    "standard length CASE 4",
-			fields:  fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Data: []byte{0x01, 0x02}, Ne: 3},
-			want:    []byte{0x00, 0xA4, 0x04, 0x01, 0x02, 0x01, 0x02, 0x03},
-			wantErr: false,
+			name:         "small Case 4 encoded extended",
+			c:            []byte{0x00, 0xA4, 0x04, 0x00, 0x00, 0x00, 0x02, 0xA0, 0x00, 0x01, 0x00},
+			want:         &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0xA0, 0x00}, Ne: 256},
+			wantExtended: true,
+			wantErr:      false,
 		},
 		{
-			name:    "extended length CASE 2 LE unequal zero",
-			fields:  fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Ne: 65535},
-			want:    []byte{0x00, 0xA4, 0x04, 0x01, 0x00, 0xFF, 0xFF},
-			wantErr: false,
+			name:         "error: invalid",
+			c:            []byte{0x00, 0xA4, 0x04},
+			want:         nil,
+			wantExtended: false,
+			wantErr:      true,
 		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotExtended, err := ParseCapduWithLengthFormat(tt.c)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseCapduWithLengthFormat() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseCapduWithLengthFormat() got = %v, want %v", got, tt.want)
+			}
+
+			if gotExtended != tt.wantExtended {
+				t.Errorf("ParseCapduWithLengthFormat() gotExtended = %v, want %v", gotExtended, tt.wantExtended)
+			}
+
+			if got != nil && gotExtended && got.IsExtendedLength() {
+				t.Errorf("IsExtendedLength() = true for a command that fits into the standard length format")
+			}
+		})
+	}
+}
+
+func TestParseCapduBestEffort(t *testing.T) {
+	tests := []struct {
+		name         string
+		c            []byte
+		want         *Capdu
+		wantWarnings int
+		wantErr      bool
+	}{
 		{
-			name:    "extended length CASE 2 LE equal zero",
-			fields:  fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Ne: 65536},
-			want:    []byte{0x00, 0xA4, 0x04, 0x01, 0x00, 0x00, 0x00},
-			wantErr: false,
+			name:         "well-formed",
+			c:            []byte{0x00, 0xA4, 0x04, 0x00, 0x02, 0x01, 0x02, 0x00},
+			want:         &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0x01, 0x02}, Ne: 256},
+			wantWarnings: 0,
+			wantErr:      false,
 		},
 		{
-			name:    "error: ne invalid CASE 2",
-			fields:  fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Ne: 65537},
-			want:    nil,
-			wantErr: true,
+			name:         "standard Lc too big",
+			c:            []byte{0x00, 0xA4, 0x04, 0x00, 0x05, 0x01, 0x02},
+			want:         &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0x01, 0x02}},
+			wantWarnings: 1,
+			wantErr:      false,
 		},
 		{
-			name:    "extended length CASE 3",
-			fields:  fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Data: extendedData, Ne: 0},
-			want:    append([]byte{0x00, 0xA4, 0x04, 0x01, 0x00, 0xFF, 0xFF}, extendedData...),
-			wantErr: false,
+			name:         "standard Lc too small",
+			c:            []byte{0x00, 0xA4, 0x04, 0x00, 0x01, 0x01, 0x02, 0x03, 0x04},
+			want:         &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0x01}, Ne: 2},
+			wantWarnings: 2,
+			wantErr:      false,
 		},
 		{
-			name:    "error: invalid length CASE 3",
-			fields:  fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Data: tooExtendedData, Ne: 0},
-			want:    nil,
-			wantErr: true,
+			name:         "extended Lc too big",
+			c:            []byte{0x00, 0xA4, 0x04, 0x00, 0x00, 0x01, 0x00, 0x01, 0x02},
+			want:         &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0x01, 0x02}},
+			wantWarnings: 1,
+			wantErr:      false,
 		},
 		{
-			name:    "extended length CASE 4 LE unequal zero",
-			fields:  fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Data: extendedData, Ne: 65535},
-			want:    append(append([]byte{0x00, 0xA4, 0x04, 0x01, 0x00, 0xFF, 0xFF}, extendedData...), []byte{0xFF, 0xFF}...),
-			wantErr: false,
+			name:         "extended Lc too small",
+			c:            []byte{0x00, 0xA4, 0x04, 0x00, 0x00, 0x00, 0x01, 0x01, 0x00, 0x00, 0xFF},
+			want:         &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0x01}, Ne: 65536},
+			wantWarnings: 2,
+			wantErr:      false,
 		},
 		{
-			name:    "extended length CASE 4 LE equal zero",
-			fields:  fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Data: extendedData, Ne: 65536},
-			want:    append(append([]byte{0x00, 0xA4, 0x04, 0x01, 0x00, 0xFF, 0xFF}, extendedData...), []byte{0x00, 0x00}...),
-			wantErr: false,
+			name:         "extended Lc too small without Le",
+			c:            []byte{0x00, 0xA4, 0x04, 0x00, 0x00, 0x00, 0x01, 0x01, 0xFF},
+			want:         &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0x01}},
+			wantWarnings: 2,
+			wantErr:      false,
 		},
 		{
-			name:    "error: data extended length CASE 4",
-			fields:  fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Data: tooExtendedData, Ne: 255},
-			want:    nil,
-			wantErr: true,
+			name:         "extended Lc zero",
+			c:            []byte{0x00, 0xB0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01},
+			want:         &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 65536},
+			wantWarnings: 2,
+			wantErr:      false,
 		},
 		{
-			name:    "error: ne invalid length CASE 4",
-			fields:  fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Data: tooExtendedData, Ne: 65537},
+			name:         "standard Lc zero",
+			c:            []byte{0x00, 0xB0, 0x00, 0x00, 0x00, 0x10},
+			want:         &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 256},
+			wantWarnings: 2,
+			wantErr:      false,
+		},
+		{
+			name:         "exceeds maximum length",
+			c:            append(append([]byte{0x00, 0xA4, 0x04, 0x00, 0x00, 0xFF, 0xFF}, make([]byte, 65535)...), 0x00, 0x00, 0xFF),
+			want:         &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: make([]byte, 65535), Ne: 65536},
+			wantWarnings: 1,
+			wantErr:      false,
+		},
+		{
+			name:         "error: shorter than header",
+			c:            []byte{0x00, 0xA4, 0x04},
+			want:         nil,
+			wantWarnings: 0,
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, warnings, err := ParseCapduBestEffort(tt.c)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseCapduBestEffort() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseCapduBestEffort() got = %v, want %v", got, tt.want)
+			}
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("ParseCapduBestEffort() warnings = %v, want %d warnings", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestParseCapduParts(t *testing.T) {
+	type args struct {
+		header []byte
+		body   []byte
+	}
+
+	tests := []struct {
+		name    string
+		args    args
+		want    *Capdu
+		wantErr bool
+	}{
+		{
+			name:    "error: header too short",
+			args:    args{header: []byte{0x00, 0xA4, 0x04}, body: []byte{0x00}},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: header too long",
+			args:    args{header: []byte{0x00, 0xA4, 0x04, 0x00, 0x00}, body: nil},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: body too long",
+			args:    args{header: []byte{0x00, 0xA4, 0x04, 0x00}, body: make([]byte, 65541)},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: invalid Lc",
+			args:    args{header: []byte{0x00, 0xA4, 0x04, 0x00}, body: []byte{0x05, 0x01}},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "Case 1 nil body",
+			args:    args{header: []byte{0x00, 0xA4, 0x04, 0x00}, body: nil},
+			want:    &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00},
+			wantErr: false,
+		},
+		{
+			name:    "Case 1 empty body",
+			args:    args{header: []byte{0x00, 0xA4, 0x04, 0x00}, body: []byte{}},
+			want:    &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00},
+			wantErr: false,
+		},
+		{
+			name:    "Case 2 standard length",
+			args:    args{header: []byte{0x00, 0xA4, 0x04, 0x00}, body: []byte{0x00}},
+			want:    &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Ne: 256},
+			wantErr: false,
+		},
+		{
+			name:    "Case 4 standard length",
+			args:    args{header: []byte{0x00, 0xA4, 0x04, 0x00}, body: []byte{0x02, 0x01, 0x02, 0x00}},
+			want:    &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0x01, 0x02}, Ne: 256},
+			wantErr: false,
+		},
+		{
+			name:    "Case 4 extended length",
+			args:    args{header: []byte{0x00, 0xA4, 0x04, 0x00}, body: []byte{0x00, 0x00, 0x02, 0x01, 0x02, 0x01, 0x01}},
+			want:    &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0x01, 0x02}, Ne: 257},
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCapduParts(tt.args.header, tt.args.body)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseCapduParts() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseCapduParts() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCapduHexString(t *testing.T) {
+	maxData := make([]byte, MaxLenCommandDataExtended)
+	maxCapdu := append(append([]byte{0x00, 0xA4, 0x04, 0x00, 0x00, 0xFF, 0xFF}, maxData...), 0x00, 0x00)
+	maxCapduHex := hex.EncodeToString(maxCapdu)
+
+	type args struct {
+		s string
+	}
+
+	tests := []struct {
+		name    string
+		args    args
+		want    *Capdu
+		wantErr bool
+	}{
+		{
+			name:    "error: uneven number bytes",
+			args:    args{s: "000102030"},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: invalid length",
+			args:    args{s: "000102"},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: invalid characters",
+			args:    args{"s:00010203GG"},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: below minimum length",
+			args:    args{s: "00A404"},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: exceeds maximum length",
+			args:    args{s: maxCapduHex + "00"},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "standard length CASE 1",
+			args:    args{s: "00A40401"},
+			want:    &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Ne: 0},
+			wantErr: false,
+		},
+		{
+			name:    "maximum length",
+			args:    args{s: maxCapduHex},
+			want:    &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: maxData, Ne: 65536},
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCapduHexString(tt.args.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseCapduHexString() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseCapduHexString() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCapduBase64(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    *Capdu
+		wantErr bool
+	}{
+		{
+			name:    "Case 4",
+			s:       "gPLgAgJPAAA=",
+			want:    &Capdu{Cla: 0x80, Ins: 0xF2, P1: 0xE0, P2: 0x02, Data: []byte{0x4F, 0x00}, Ne: 256},
+			wantErr: false,
+		},
+		{
+			name:    "error: invalid base64",
+			s:       "gPLgAgJPAAA",
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: too short",
+			s:       "gPLg",
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: too long",
+			s:       base64.StdEncoding.EncodeToString(make([]byte, MaxLenCapdu+1)),
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCapduBase64(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseCapduBase64() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseCapduBase64() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapdu_Base64_RoundTrip(t *testing.T) {
+	capdus := []*Capdu{
+		{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00},
+		{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 65536},
+		{Cla: 0x80, Ins: 0xF2, P1: 0xE0, P2: 0x02, Data: []byte{0x4F, 0x00}, Ne: 256},
+	}
+
+	for _, c := range capdus {
+		s, err := c.Base64()
+		if err != nil {
+			t.Fatalf("Base64() error = %v", err)
+		}
+
+		got, err := ParseCapduBase64(s)
+		if err != nil {
+			t.Fatalf("ParseCapduBase64() error = %v", err)
+		}
+
+		if !reflect.DeepEqual(got, c) {
+			t.Errorf("round trip got = %v, want %v", got, c)
+		}
+	}
+
+	if _, err := (&Capdu{Ne: 65537}).Base64(); err == nil {
+		t.Errorf("Base64() expected error for invalid Capdu")
+	}
+}
+
+func TestParseRapduBase64(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    *Rapdu
+		wantErr bool
+	}{
+		{
+			name:    "data and SW",
+			s:       "AQIDkAA=",
+			want:    &Rapdu{Data: []byte{0x01, 0x02, 0x03}, SW1: 0x90, SW2: 0x00},
+			wantErr: false,
+		},
+		{
+			name:    "error: invalid base64",
+			s:       "AQ*DkAA=",
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: too short",
+			s:       "kA==",
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: too long",
+			s:       base64.StdEncoding.EncodeToString(make([]byte, MaxLenRapdu+1)),
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRapduBase64(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseRapduBase64() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRapduBase64() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRapdu_Base64_RoundTrip(t *testing.T) {
+	rapdus := []*Rapdu{
+		{SW1: 0x6A, SW2: 0x82},
+		{Data: []byte{0x01, 0x02, 0x03}, SW1: 0x90, SW2: 0x00},
+	}
+
+	for _, r := range rapdus {
+		s, err := r.Base64()
+		if err != nil {
+			t.Fatalf("Base64() error = %v", err)
+		}
+
+		got, err := ParseRapduBase64(s)
+		if err != nil {
+			t.Fatalf("ParseRapduBase64() error = %v", err)
+		}
+
+		if !reflect.DeepEqual(got, r) {
+			t.Errorf("round trip got = %v, want %v", got, r)
+		}
+	}
+
+	if _, err := (&Rapdu{Data: make([]byte, 65537)}).Base64(); err == nil {
+		t.Errorf("Base64() expected error for invalid Rapdu")
+	}
+}
+
+func TestNewRapdu(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		sw1  byte
+		sw2  byte
+		want *Rapdu
+	}{
+		{
+			name: "data and status word",
+			data: []byte{0x01, 0x02, 0x03},
+			sw1:  0x90,
+			sw2:  0x00,
+			want: &Rapdu{Data: []byte{0x01, 0x02, 0x03}, SW1: 0x90, SW2: 0x00},
+		},
+		{
+			name: "status word only",
+			data: nil,
+			sw1:  0x6A,
+			sw2:  0x82,
+			want: &Rapdu{SW1: 0x6A, SW2: 0x82},
+		},
+		{
+			name: "status word only, empty data",
+			data: []byte{},
+			sw1:  0x90,
+			sw2:  0x00,
+			want: &Rapdu{SW1: 0x90, SW2: 0x00},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewRapdu(tt.data, tt.sw1, tt.sw2); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewRapdu() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRapdu_CopiesData(t *testing.T) {
+	data := []byte{0x01, 0x02}
+
+	r := NewRapdu(data, 0x90, 0x00)
+	data[0] = 0xFF
+
+	if r.Data[0] != 0x01 {
+		t.Errorf("NewRapdu() Data = %X, modification of data must not affect the Rapdu", r.Data)
+	}
+}
+
+func TestNewRapduFromParts(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		sw   uint16
+		want *Rapdu
+	}{
+		{
+			name: "data and status word",
+			data: []byte{0x01, 0x02, 0x03},
+			sw:   0x9000,
+			want: &Rapdu{Data: []byte{0x01, 0x02, 0x03}, SW1: 0x90, SW2: 0x00},
+		},
+		{
+			name: "status word only",
+			data: nil,
+			sw:   0x6CF0,
+			want: &Rapdu{SW1: 0x6C, SW2: 0xF0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewRapduFromParts(tt.data, tt.sw); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewRapduFromParts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRapdu(t *testing.T) {
+	type args struct {
+		b []byte
+	}
+
+	tests := []struct {
+		name    string
+		args    args
+		want    *Rapdu
+		wantErr bool
+	}{
+		{
+			name:    "error: invalid length too small",
+			args:    args{b: []byte{0x6A}},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: invalid length too big",
+			args:    args{b: make([]byte, 65539)},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "only SW",
+			args:    args{b: []byte{0x6A, 0x80}},
+			want:    &Rapdu{Data: nil, SW1: 0x6A, SW2: 0x80},
+			wantErr: false,
+		},
+		{
+			name:    "data and SW",
+			args:    args{b: []byte{0x01, 0x02, 0x03, 0x90, 0x00}},
+			want:    &Rapdu{Data: []byte{0x01, 0x02, 0x03}, SW1: 0x90, SW2: 0x00},
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRapdu(tt.args.b)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseRapdu() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRapdu() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRapduChecked(t *testing.T) {
+	tests := []struct {
+		name        string
+		b           []byte
+		want        *Rapdu
+		wantAnomaly bool
+		wantErr     bool
+	}{
+		{
+			name:        "success with data",
+			b:           []byte{0x01, 0x02, 0x90, 0x00},
+			want:        &Rapdu{Data: []byte{0x01, 0x02}, SW1: 0x90, SW2: 0x00},
+			wantAnomaly: false,
+			wantErr:     false,
+		},
+		{
+			name:        "error without data",
+			b:           []byte{0x6A, 0x82},
+			want:        &Rapdu{SW1: 0x6A, SW2: 0x82},
+			wantAnomaly: false,
+			wantErr:     false,
+		},
+		{
+			name:        "warning with data",
+			b:           []byte{0x01, 0x62, 0x82},
+			want:        &Rapdu{Data: []byte{0x01}, SW1: 0x62, SW2: 0x82},
+			wantAnomaly: false,
+			wantErr:     false,
+		},
+		{
+			name:        "error with data",
+			b:           []byte{0xDE, 0xAD, 0x6F, 0x00},
+			want:        &Rapdu{Data: []byte{0xDE, 0xAD}, SW1: 0x6F, SW2: 0x00},
+			wantAnomaly: true,
+			wantErr:     false,
+		},
+		{
+			name:        "error: too short",
+			b:           []byte{0x90},
+			want:        nil,
+			wantAnomaly: false,
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotAnomaly, err := ParseRapduChecked(tt.b)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseRapduChecked() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRapduChecked() got = %v, want %v", got, tt.want)
+			}
+
+			if gotAnomaly != tt.wantAnomaly {
+				t.Errorf("ParseRapduChecked() gotAnomaly = %v, want %v", gotAnomaly, tt.wantAnomaly)
+			}
+		})
+	}
+}
+
+func TestParseRapduSWFirst(t *testing.T) {
+	tests := []struct {
+		name    string
+		b       []byte
+		want    *Rapdu
+		wantErr bool
+	}{
+		{
+			name:    "error: invalid length too small",
+			b:       []byte{0x90},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: invalid length too big",
+			b:       make([]byte, 65539),
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "only SW",
+			b:       []byte{0x6A, 0x82},
+			want:    &Rapdu{Data: nil, SW1: 0x6A, SW2: 0x82},
+			wantErr: false,
+		},
+		{
+			name:    "SW and data",
+			b:       []byte{0x90, 0x00, 0x01, 0x02, 0x03},
+			want:    &Rapdu{Data: []byte{0x01, 0x02, 0x03}, SW1: 0x90, SW2: 0x00},
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRapduSWFirst(tt.b)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseRapduSWFirst() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRapduSWFirst() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRapduHexString(t *testing.T) {
+	maxData := make([]byte, MaxLenResponseDataExtended)
+	maxRapduHex := hex.EncodeToString(maxData) + "9000"
+
+	type args struct {
+		s string
+	}
+
+	tests := []struct {
+		name    string
+		args    args
+		want    *Rapdu
+		wantErr bool
+	}{
+		{
+			name:    "error: uneven number bytes",
+			args:    args{s: "6A80A"},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: invalid length",
+			args:    args{s: "6A"},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: invalid characters",
+			args:    args{s: "FFGF6A88"},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "only SW",
+			args:    args{s: "6A80"},
+			want:    &Rapdu{Data: nil, SW1: 0x6A, SW2: 0x80},
+			wantErr: false,
+		},
+		{
+			name:    "error: exceeds maximum length",
+			args:    args{s: "00" + maxRapduHex},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "data and SW",
+			args:    args{s: "0102039000"},
+			want:    &Rapdu{Data: []byte{0x01, 0x02, 0x03}, SW1: 0x90, SW2: 0x00},
+			wantErr: false,
+		},
+		{
+			name:    "maximum length",
+			args:    args{s: maxRapduHex},
+			want:    &Rapdu{Data: maxData, SW1: 0x90, SW2: 0x00},
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRapduHexString(tt.args.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseRapduHexString() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRapduHexString() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapdu_Bytes(t *testing.T) {
+	extendedData := make([]byte, 65535)
+	for i := range extendedData {
+		extendedData[i] = 0xFF
+	}
+
+	tooExtendedData := make([]byte, 65536)
+
+	type fields struct {
+		Cla  byte
+		Ins  byte
+		P1   byte
+		P2   byte
+		Data []byte
+		Ne   int
+	}
+
+	tests := []struct {
+		name    string
+		fields  fields
+		want    []byte
+		wantErr bool
+	}{
+		{
+			name:    "standard length CASE 1",
+			fields:  fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Ne: 0},
+			want:    []byte{0x00, 0xA4, 0x04, 0x01},
+			wantErr: false,
+		},
+		{
+			name:    "standard length CASE 2 LE unequal zero",
+			fields:  fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Ne: 255},
+			want:    []byte{0x00, 0xA4, 0x04, 0x01, 0xFF},
+			wantErr: false,
+		},
+		{
+			name:    "standard length CASE 2 LE equal zero",
+			fields:  fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Ne: 256},
+			want:    []byte{0x00, 0xA4, 0x04, 0x01, 0x00},
+			wantErr: false,
+		},
+		{
+			name:    "standard length CASE 3",
+			fields:  fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Data: []byte{0x01, 0x02, 0x3}, Ne: 0},
+			want:    []byte{0x00, 0xA4, 0x04, 0x01, 0x03, 0x01, 0x02, 0x03},
+			wantErr: false,
+		},
+		{
+			name:    "standard length CASE 4",
+			fields:  fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Data: []byte{0x01, 0x02}, Ne: 3},
+			want:    []byte{0x00, 0xA4, 0x04, 0x01, 0x02, 0x01, 0x02, 0x03},
+			wantErr: false,
+		},
+		{
+			name:    "extended length CASE 2 LE unequal zero",
+			fields:  fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Ne: 65535},
+			want:    []byte{0x00, 0xA4, 0x04, 0x01, 0x00, 0xFF, 0xFF},
+			wantErr: false,
+		},
+		{
+			name:    "extended length CASE 2 LE equal zero",
+			fields:  fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Ne: 65536},
+			want:    []byte{0x00, 0xA4, 0x04, 0x01, 0x00, 0x00, 0x00},
+			wantErr: false,
+		},
+		{
+			name:    "error: ne invalid CASE 2",
+			fields:  fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Ne: 65537},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: negative ne",
+			fields:  fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Ne: -1},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: negative ne with data",
+			fields:  fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Data: []byte{0x01}, Ne: -1},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "extended length CASE 3",
+			fields:  fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Data: extendedData, Ne: 0},
+			want:    append([]byte{0x00, 0xA4, 0x04, 0x01, 0x00, 0xFF, 0xFF}, extendedData...),
+			wantErr: false,
+		},
+		{
+			name:    "error: invalid length CASE 3",
+			fields:  fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Data: tooExtendedData, Ne: 0},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "extended length CASE 4 LE unequal zero",
+			fields:  fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Data: extendedData, Ne: 65535},
+			want:    append(append([]byte{0x00, 0xA4, 0x04, 0x01, 0x00, 0xFF, 0xFF}, extendedData...), []byte{0xFF, 0xFF}...),
+			wantErr: false,
+		},
+		{
+			name:    "extended length CASE 4 LE equal zero",
+			fields:  fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Data: extendedData, Ne: 65536},
+			want:    append(append([]byte{0x00, 0xA4, 0x04, 0x01, 0x00, 0xFF, 0xFF}, extendedData...), []byte{0x00, 0x00}...),
+			wantErr: false,
+		},
+		{
+			name:    "error: data extended length CASE 4",
+			fields:  fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Data: tooExtendedData, Ne: 255},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: ne invalid length CASE 4",
+			fields:  fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Data: tooExtendedData, Ne: 65537},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Capdu{
+				Cla:  tt.fields.Cla,
+				Ins:  tt.fields.Ins,
+				P1:   tt.fields.P1,
+				P2:   tt.fields.P2,
+				Data: tt.fields.Data,
+				Ne:   tt.fields.Ne,
+			}
+			got, err := c.Bytes()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Bytes() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Bytes() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapdu_IsCanonicalEncoding(t *testing.T) {
+	tests := []struct {
+		name    string
+		capdu   *Capdu
+		raw     []byte
+		want    bool
+		wantErr bool
+	}{
+		{
+			name:    "canonical standard",
+			capdu:   &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0x01, 0x02}, Ne: 256},
+			raw:     []byte{0x00, 0xA4, 0x04, 0x00, 0x02, 0x01, 0x02, 0x00},
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "canonical extended",
+			capdu:   &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 65536},
+			raw:     []byte{0x00, 0xB0, 0x00, 0x00, 0x00, 0x00, 0x00},
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "non-canonical extended where standard suffices",
+			capdu:   &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0x01, 0x02}, Ne: 256},
+			raw:     []byte{0x00, 0xA4, 0x04, 0x00, 0x00, 0x00, 0x02, 0x01, 0x02, 0x01, 0x00},
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name:    "non-canonical extended Le for Case 2",
+			capdu:   &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 256},
+			raw:     []byte{0x00, 0xB0, 0x00, 0x00, 0x00, 0x01, 0x00},
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name:    "error: raw encodes a different command",
+			capdu:   &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 256},
+			raw:     []byte{0x00, 0xB0, 0x00, 0x00, 0x10},
+			want:    false,
+			wantErr: true,
+		},
+		{
+			name:    "error: invalid raw",
+			capdu:   &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 256},
+			raw:     []byte{0x00, 0xB0},
+			want:    false,
+			wantErr: true,
+		},
+		{
+			name:    "error: invalid Capdu",
+			capdu:   &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 65537},
+			raw:     []byte{0x00, 0xB0, 0x00, 0x00, 0x00},
+			want:    false,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.capdu.IsCanonicalEncoding(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("IsCanonicalEncoding() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+			if got != tt.want {
+				t.Errorf("IsCanonicalEncoding() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapdu_BytesMinimal(t *testing.T) {
+	tests := []struct {
+		name    string
+		capdu   Capdu
+		want    []byte
+		wantErr bool
+	}{
+		{
+			name:    "Case 2 standard length max Ne omitted",
+			capdu:   Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 256},
+			want:    []byte{0x00, 0xB0, 0x00, 0x00},
+			wantErr: false,
+		},
+		{
+			name:    "Case 2 standard length Ne kept",
+			capdu:   Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 255},
+			want:    []byte{0x00, 0xB0, 0x00, 0x00, 0xFF},
+			wantErr: false,
+		},
+		{
+			name:    "Case 2 extended length max Ne omitted",
+			capdu:   Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 65536},
+			want:    []byte{0x00, 0xB0, 0x00, 0x00},
+			wantErr: false,
+		},
+		{
+			name:    "Case 2 extended length Ne kept",
+			capdu:   Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 257},
+			want:    []byte{0x00, 0xB0, 0x00, 0x00, 0x00, 0x01, 0x01},
+			wantErr: false,
+		},
+		{
+			name:    "Case 4 standard length max Ne omitted",
+			capdu:   Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0x01, 0x02}, Ne: 256},
+			want:    []byte{0x00, 0xA4, 0x04, 0x00, 0x02, 0x01, 0x02},
+			wantErr: false,
+		},
+		{
+			name:    "Case 4 extended length data, standard max Ne kept",
+			capdu:   Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: make([]byte, 256), Ne: 256},
+			want:    append(append([]byte{0x00, 0xA4, 0x04, 0x00, 0x00, 0x01, 0x00}, make([]byte, 256)...), 0x01, 0x00),
+			wantErr: false,
+		},
+		{
+			name:    "error: invalid Ne",
+			capdu:   Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 65537},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.capdu.BytesMinimal()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("BytesMinimal() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BytesMinimal() got = %X, want %X", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapdu_WithNe(t *testing.T) {
+	tests := []struct {
+		name  string
+		capdu *Capdu
+		ne    int
+		want  []byte
+	}{
+		{
+			name:  "Case 1 to Case 2",
+			capdu: &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00},
+			ne:    256,
+			want:  []byte{0x00, 0xB0, 0x00, 0x00, 0x00},
+		},
+		{
+			name:  "Case 3 to Case 4",
+			capdu: &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0xA0, 0x00}},
+			ne:    16,
+			want:  []byte{0x00, 0xA4, 0x04, 0x00, 0x02, 0xA0, 0x00, 0x10},
+		},
+		{
+			name:  "Case 4 to Case 3",
+			capdu: &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0xA0, 0x00}, Ne: 256},
+			ne:    0,
+			want:  []byte{0x00, 0xA4, 0x04, 0x00, 0x02, 0xA0, 0x00},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := tt.capdu.clone()
+
+			got := tt.capdu.WithNe(tt.ne)
+
+			b, err := got.Bytes()
+			if err != nil {
+				t.Fatalf("Bytes() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(b, tt.want) {
+				t.Errorf("WithNe() got = %X, want %X", b, tt.want)
+			}
+
+			if !reflect.DeepEqual(tt.capdu, orig) {
+				t.Errorf("WithNe() modified original Capdu")
+			}
+
+			if len(got.Data) > 0 && &got.Data[0] == &tt.capdu.Data[0] {
+				t.Errorf("WithNe() did not copy Data")
+			}
+		})
+	}
+}
+
+func TestCapdu_RetryWithLe(t *testing.T) {
+	tests := []struct {
+		name   string
+		capdu  *Capdu
+		le     int
+		wantNe int
+		want   []byte
+	}{
+		{
+			name:   "Case 2",
+			capdu:  &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 256},
+			le:     0x10,
+			wantNe: 16,
+			want:   []byte{0x00, 0xB0, 0x00, 0x00, 0x10},
+		},
+		{
+			name:   "Case 4",
+			capdu:  &Capdu{Cla: 0x00, Ins: 0xCB, P1: 0x3F, P2: 0xFF, Data: []byte{0x5C, 0x00}, Ne: 1},
+			le:     0x20,
+			wantNe: 32,
+			want:   []byte{0x00, 0xCB, 0x3F, 0xFF, 0x02, 0x5C, 0x00, 0x20},
+		},
+		{
+			name:   "le 0 means 256",
+			capdu:  &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 1},
+			le:     0,
+			wantNe: 256,
+			want:   []byte{0x00, 0xB0, 0x00, 0x00, 0x00},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := tt.capdu.clone()
+
+			got := tt.capdu.RetryWithLe(tt.le)
+			if got.Ne != tt.wantNe {
+				t.Errorf("RetryWithLe() got Ne = %d, want %d", got.Ne, tt.wantNe)
+			}
+
+			b, err := got.Bytes()
+			if err != nil {
+				t.Fatalf("Bytes() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(b, tt.want) {
+				t.Errorf("RetryWithLe() got = %X, want %X", b, tt.want)
+			}
+
+			if !reflect.DeepEqual(tt.capdu, orig) {
+				t.Errorf("RetryWithLe() modified original Capdu")
+			}
+
+			if len(got.Data) > 0 && &got.Data[0] == &tt.capdu.Data[0] {
+				t.Errorf("RetryWithLe() did not copy Data")
+			}
+		})
+	}
+}
+
+func TestCapdu_WithInstruction(t *testing.T) {
+	tests := []struct {
+		name  string
+		capdu *Capdu
+		ins   byte
+		want  *Capdu
+	}{
+		{
+			name:  "Case 1",
+			capdu: &Capdu{Cla: 0x00, Ins: 0x70, P1: 0x00, P2: 0x00},
+			ins:   0x71,
+			want:  &Capdu{Cla: 0x00, Ins: 0x71, P1: 0x00, P2: 0x00},
+		},
+		{
+			name:  "Case 4",
+			capdu: &Capdu{Cla: 0x80, Ins: 0xCA, P1: 0x9F, P2: 0x7F, Data: []byte{0x01, 0x02}, Ne: 256},
+			ins:   0xCB,
+			want:  &Capdu{Cla: 0x80, Ins: 0xCB, P1: 0x9F, P2: 0x7F, Data: []byte{0x01, 0x02}, Ne: 256},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := tt.capdu.clone()
+
+			got := tt.capdu.WithInstruction(tt.ins)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WithInstruction() got = %v, want %v", got, tt.want)
+			}
+
+			if !reflect.DeepEqual(tt.capdu, orig) {
+				t.Errorf("WithInstruction() modified original Capdu")
+			}
+
+			if len(got.Data) > 0 && &got.Data[0] == &tt.capdu.Data[0] {
+				t.Errorf("WithInstruction() did not copy Data")
+			}
+		})
+	}
+}
+
+func TestCapdu_WithP1P2(t *testing.T) {
+	tests := []struct {
+		name  string
+		capdu *Capdu
+		p1    byte
+		p2    byte
+		want  *Capdu
+	}{
+		{
+			name:  "Case 2",
+			capdu: &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 16},
+			p1:    0x81,
+			p2:    0x10,
+			want:  &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x81, P2: 0x10, Ne: 16},
+		},
+		{
+			name:  "Case 3",
+			capdu: &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0xA0, 0x00}},
+			p1:    0x04,
+			p2:    0x02,
+			want:  &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x02, Data: []byte{0xA0, 0x00}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := tt.capdu.clone()
+
+			got := tt.capdu.WithP1P2(tt.p1, tt.p2)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WithP1P2() got = %v, want %v", got, tt.want)
+			}
+
+			if !reflect.DeepEqual(tt.capdu, orig) {
+				t.Errorf("WithP1P2() modified original Capdu")
+			}
+
+			if len(got.Data) > 0 && &got.Data[0] == &tt.capdu.Data[0] {
+				t.Errorf("WithP1P2() did not copy Data")
+			}
+		})
+	}
+}
+
+func TestCapdu_EnsureLe(t *testing.T) {
+	tests := []struct {
+		name  string
+		capdu *Capdu
+		want  []byte
+	}{
+		{
+			name:  "Case 3 to Case 4",
+			capdu: &Capdu{Cla: 0x00, Ins: 0xD6, P1: 0x00, P2: 0x00, Data: []byte{0x01, 0x02}},
+			want:  []byte{0x00, 0xD6, 0x00, 0x00, 0x02, 0x01, 0x02, 0x00},
+		},
+		{
+			name:  "extended Case 3 to extended Case 4",
+			capdu: &Capdu{Cla: 0x00, Ins: 0xD6, P1: 0x00, P2: 0x00, Data: make([]byte, 256)},
+			want:  append(append([]byte{0x00, 0xD6, 0x00, 0x00, 0x00, 0x01, 0x00}, make([]byte, 256)...), 0x00, 0x00),
+		},
+		{
+			name:  "Case 1 unchanged",
+			capdu: &Capdu{Cla: 0x00, Ins: 0x70, P1: 0x00, P2: 0x00},
+			want:  []byte{0x00, 0x70, 0x00, 0x00},
+		},
+		{
+			name:  "Case 4 unchanged",
+			capdu: &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0xA0, 0x00}, Ne: 16},
+			want:  []byte{0x00, 0xA4, 0x04, 0x00, 0x02, 0xA0, 0x00, 0x10},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := tt.capdu.clone()
+
+			b, err := tt.capdu.EnsureLe().Bytes()
+			if err != nil {
+				t.Fatalf("Bytes() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(b, tt.want) {
+				t.Errorf("EnsureLe() got = %X, want %X", b, tt.want)
+			}
+
+			if !reflect.DeepEqual(tt.capdu, orig) {
+				t.Errorf("EnsureLe() modified original Capdu")
+			}
+		})
+	}
+}
+
+func TestCapdu_EnsureLe_NegativeNe(t *testing.T) {
+	c := &Capdu{Cla: 0x00, Ins: 0xD6, P1: 0x00, P2: 0x00, Data: []byte{0x01}, Ne: -1}
+
+	if got := c.EnsureLe(); got.Ne != -1 {
+		t.Errorf("EnsureLe() got Ne = %d, want invalid Ne -1 to be retained", got.Ne)
+	}
+}
+
+func TestCapdu_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		capdu *Capdu
+	}{
+		{name: "Case 1", capdu: &Capdu{Cla: 0x00, Ins: 0x70, P1: 0x80, P2: 0x01}},
+		{name: "Case 1 with empty data", capdu: &Capdu{Cla: 0x00, Ins: 0x70, P1: 0x80, P2: 0x01, Data: []byte{}}},
+		{name: "standard Case 2", capdu: &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 1}},
+		{name: "standard Case 2 max", capdu: &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 256}},
+		{name: "extended Case 2", capdu: &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 257}},
+		{name: "extended Case 2 max", capdu: &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 65536}},
+		{name: "standard Case 3", capdu: &Capdu{Cla: 0x00, Ins: 0xD6, P1: 0x00, P2: 0x00, Data: []byte{0x01}}},
+		{name: "standard Case 3 max", capdu: &Capdu{Cla: 0x00, Ins: 0xD6, P1: 0x00, P2: 0x00, Data: make([]byte, 255)}},
+		{name: "extended Case 3", capdu: &Capdu{Cla: 0x00, Ins: 0xD6, P1: 0x00, P2: 0x00, Data: make([]byte, 256)}},
+		{name: "extended Case 3 max", capdu: &Capdu{Cla: 0x00, Ins: 0xD6, P1: 0x00, P2: 0x00, Data: make([]byte, 65535)}},
+		{name: "standard Case 4", capdu: &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0xA0, 0x00}, Ne: 1}},
+		{name: "standard Case 4 max", capdu: &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: make([]byte, 255), Ne: 256}},
+		{name: "extended Case 4 because of data", capdu: &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: make([]byte, 256), Ne: 1}},
+		{name: "extended Case 4 because of Ne", capdu: &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0x01}, Ne: 257}},
+		{name: "extended Case 4 max", capdu: &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: make([]byte, 65535), Ne: 65536}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := tt.capdu.Bytes()
+			if err != nil {
+				t.Fatalf("Bytes() error = %v", err)
+			}
+
+			got, err := ParseCapdu(b)
+			if err != nil {
+				t.Fatalf("ParseCapdu() error = %v", err)
+			}
+
+			want := tt.capdu.Normalize()
+			if !got.Equal(want) || !reflect.DeepEqual(got, want) {
+				t.Errorf("ParseCapdu(Bytes()) got = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestCapdu_Normalize(t *testing.T) {
+	tests := []struct {
+		name  string
+		capdu *Capdu
+		want  *Capdu
+	}{
+		{
+			name:  "already normalized",
+			capdu: &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0xA0}, Ne: 256},
+			want:  &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0xA0}, Ne: 256},
+		},
+		{
+			name:  "empty data",
+			capdu: &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Data: []byte{}, Ne: 16},
+			want:  &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Data: nil, Ne: 16},
+		},
+		{
+			name:  "invalid negative Ne is retained",
+			capdu: &Capdu{Cla: 0x00, Ins: 0xD6, P1: 0x00, P2: 0x00, Data: []byte{}, Ne: -1},
+			want:  &Capdu{Cla: 0x00, Ins: 0xD6, P1: 0x00, P2: 0x00, Data: nil, Ne: -1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := tt.capdu.clone()
+
+			if got := tt.capdu.Normalize(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Normalize() got = %v, want %v", got, tt.want)
+			}
+
+			if !reflect.DeepEqual(tt.capdu, orig) {
+				t.Errorf("Normalize() modified original Capdu")
+			}
+		})
+	}
+}
+
+func TestCapdu_Equal(t *testing.T) {
+	tests := []struct {
+		name  string
+		c     *Capdu
+		other *Capdu
+		want  bool
+	}{
+		{
+			name:  "equal",
+			c:     &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0x01}, Ne: 256},
+			other: &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0x01}, Ne: 256},
+			want:  true,
+		},
+		{
+			name:  "nil and empty data",
+			c:     &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: nil},
+			other: &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{}},
+			want:  true,
+		},
+		{
+			name:  "different data",
+			c:     &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0x01}},
+			other: &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0x02}},
+			want:  false,
+		},
+		{
+			name:  "different Ne",
+			c:     &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Ne: 255},
+			other: &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Ne: 256},
+			want:  false,
+		},
+		{
+			name:  "different header",
+			c:     &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00},
+			other: &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x0C},
+			want:  false,
+		},
+		{
+			name:  "both nil",
+			c:     nil,
+			other: nil,
+			want:  true,
+		},
+		{
+			name:  "other nil",
+			c:     &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00},
+			other: nil,
+			want:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.c.Equal(tt.other); got != tt.want {
+				t.Errorf("Equal() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapdu_EqualIgnoreChannel(t *testing.T) {
+	tests := []struct {
+		name  string
+		c     *Capdu
+		other *Capdu
+		want  bool
+	}{
+		{
+			name:  "channel 0 and 2",
+			c:     &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0xA0, 0x00}, Ne: 256},
+			other: &Capdu{Cla: 0x02, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0xA0, 0x00}, Ne: 256},
+			want:  true,
+		},
+		{
+			name:  "proprietary channel 0 and 2 with SM",
+			c:     &Capdu{Cla: 0x84, Ins: 0xF2, P1: 0x40, P2: 0x00, Data: []byte{0x4F, 0x00}, Ne: 256},
+			other: &Capdu{Cla: 0x86, Ins: 0xF2, P1: 0x40, P2: 0x00, Data: []byte{0x4F, 0x00}, Ne: 256},
+			want:  true,
+		},
+		{
+			name:  "channel 0 and further channel 4",
+			c:     &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 256},
+			other: &Capdu{Cla: 0x40, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 256},
+			want:  true,
+		},
+		{
+			name:  "channel 0 and 2 with different SM",
+			c:     &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00},
+			other: &Capdu{Cla: 0x0E, Ins: 0xA4, P1: 0x04, P2: 0x00},
+			want:  false,
+		},
+		{
+			name:  "channel 0 and 2 with different data",
+			c:     &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0xA0, 0x00}},
+			other: &Capdu{Cla: 0x02, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0xA0, 0x01}},
+			want:  false,
+		},
+		{
+			name:  "nil",
+			c:     &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00},
+			other: nil,
+			want:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.c.EqualIgnoreChannel(tt.other); got != tt.want {
+				t.Errorf("EqualIgnoreChannel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapdu_IsExtendedLength(t *testing.T) {
+	extendedData := make([]byte, 256)
+	for i := range extendedData {
+		extendedData[i] = 0xFF
+	}
+
+	standardData := make([]byte, 255)
+	for i := range standardData {
+		standardData[i] = 0xFF
+	}
+
+	type fields struct {
+		Cla  byte
+		Ins  byte
+		P1   byte
+		P2   byte
+		Data []byte
+		Ne   int
+	}
+
+	tests := []struct {
+		name   string
+		fields fields
+		want   bool
+	}{
+		{
+			name:   "extended length ne",
+			fields: fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Ne: 257},
+			want:   true,
+		},
+		{
+			name:   "extended length data",
+			fields: fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Data: extendedData, Ne: 256},
+			want:   true,
+		},
+		{
+			name:   "standard length",
+			fields: fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Data: standardData, Ne: 256},
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Capdu{
+				Cla:  tt.fields.Cla,
+				Ins:  tt.fields.Ins,
+				P1:   tt.fields.P1,
+				P2:   tt.fields.P2,
+				Data: tt.fields.Data,
+				Ne:   tt.fields.Ne,
+			}
+			if got := c.IsExtendedLength(); got != tt.want {
+				t.Errorf("IsExtendedLength() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapdu_Encoding(t *testing.T) {
+	tests := []struct {
+		name  string
+		capdu *Capdu
+		want  Encoding
+	}{
+		{name: "Case 1", capdu: &Capdu{Cla: 0x00, Ins: 0xA4}, want: EncodingStandard},
+		{name: "Ne 256", capdu: &Capdu{Cla: 0x00, Ins: 0xB0, Ne: 256}, want: EncodingStandard},
+		{name: "Ne 257", capdu: &Capdu{Cla: 0x00, Ins: 0xB0, Ne: 257}, want: EncodingExtended},
+		{name: "data 255 byte", capdu: &Capdu{Cla: 0x00, Ins: 0xD6, Data: make([]byte, 255)}, want: EncodingStandard},
+		{name: "data 256 byte", capdu: &Capdu{Cla: 0x00, Ins: 0xD6, Data: make([]byte, 256)}, want: EncodingExtended},
+		{name: "data 255 byte and Ne 256", capdu: &Capdu{Cla: 0x00, Ins: 0xA4, Data: make([]byte, 255), Ne: 256}, want: EncodingStandard},
+		{name: "data 1 byte and Ne 65536", capdu: &Capdu{Cla: 0x00, Ins: 0xA4, Data: []byte{0x01}, Ne: 65536}, want: EncodingExtended},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.capdu.Encoding(); got != tt.want {
+				t.Errorf("Encoding() = %v, want %v", got, tt.want)
+			}
+
+			if got := tt.capdu.IsExtendedLength(); got != (tt.want == EncodingExtended) {
+				t.Errorf("IsExtendedLength() = %v, want %v", got, tt.want == EncodingExtended)
+			}
+		})
+	}
+}
+
+func TestEncoding_String(t *testing.T) {
+	tests := []struct {
+		encoding Encoding
+		want     string
+	}{
+		{encoding: EncodingStandard, want: "standard"},
+		{encoding: EncodingExtended, want: "extended"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.encoding.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapdu_IsT0Problematic(t *testing.T) {
+	tests := []struct {
+		name       string
+		capdu      *Capdu
+		want       bool
+		wantReason string
+	}{
+		{
+			name:       "Case 1",
+			capdu:      &Capdu{Cla: 0x00, Ins: 0x44, P1: 0x00, P2: 0x00},
+			want:       false,
+			wantReason: "",
+		},
+		{
+			name:       "Case 2 standard",
+			capdu:      &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 256},
+			want:       false,
+			wantReason: "",
+		},
+		{
+			name:       "Case 3 standard",
+			capdu:      &Capdu{Cla: 0x00, Ins: 0xD6, P1: 0x00, P2: 0x00, Data: []byte{0x01}},
+			want:       false,
+			wantReason: "",
+		},
+		{
+			name:       "Case 4 standard",
+			capdu:      &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0xA0, 0x00}, Ne: 256},
+			want:       true,
+			wantReason: "Case 4 commands are transmitted without Le by T=0, response data must be retrieved with GET RESPONSE",
+		},
+		{
+			name:       "Case 4 extended",
+			capdu:      &Capdu{Cla: 0x00, Ins: 0x2A, P1: 0x9E, P2: 0x9A, Data: make([]byte, 300), Ne: 512},
+			want:       true,
+			wantReason: "extended length commands are not supported by T=0 and must be transmitted with ENVELOPE",
+		},
+		{
+			name:       "Case 2 extended",
+			capdu:      &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 65536},
+			want:       true,
+			wantReason: "extended length commands are not supported by T=0 and must be transmitted with ENVELOPE",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotReason := tt.capdu.IsT0Problematic()
+			if got != tt.want {
+				t.Errorf("IsT0Problematic() got = %v, want %v", got, tt.want)
+			}
+
+			if gotReason != tt.wantReason {
+				t.Errorf("IsT0Problematic() gotReason = %v, want %v", gotReason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestCapdu_FitsBuffer(t *testing.T) {
+	tests := []struct {
+		name    string
+		capdu   *Capdu
+		maxLen  int
+		want    bool
+		wantErr bool
+	}{
+		{
+			name:    "exactly fits",
+			capdu:   &Capdu{Cla: 0x00, Ins: 0xD6, P1: 0x00, P2: 0x00, Data: make([]byte, 250)},
+			maxLen:  255,
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "exceeds by one byte",
+			capdu:   &Capdu{Cla: 0x00, Ins: 0xD6, P1: 0x00, P2: 0x00, Data: make([]byte, 250), Ne: 1},
+			maxLen:  255,
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name:    "extended length exactly fits",
+			capdu:   &Capdu{Cla: 0x00, Ins: 0xD6, P1: 0x00, P2: 0x00, Data: make([]byte, 256)},
+			maxLen:  263,
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "extended length exceeds by one byte",
+			capdu:   &Capdu{Cla: 0x00, Ins: 0xD6, P1: 0x00, P2: 0x00, Data: make([]byte, 256)},
+			maxLen:  262,
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name:    "error: invalid Capdu",
+			capdu:   &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 65537},
+			maxLen:  65544,
+			want:    false,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.capdu.FitsBuffer(tt.maxLen)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("FitsBuffer() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+			if got != tt.want {
+				t.Errorf("FitsBuffer() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapduByteDistance(t *testing.T) {
+	tests := []struct {
+		name    string
+		a       *Capdu
+		b       *Capdu
+		want    int
+		wantErr bool
+	}{
+		{
+			name:    "identical",
+			a:       &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0xA0, 0x00}, Ne: 256},
+			b:       &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0xA0, 0x00}, Ne: 256},
+			want:    0,
+			wantErr: false,
+		},
+		{
+			name:    "different header and data",
+			a:       &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0xA0, 0x00}},
+			b:       &Capdu{Cla: 0x80, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0xA0, 0x01}},
+			want:    2,
+			wantErr: false,
+		},
+		{
+			name:    "different length",
+			a:       &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00},
+			b:       &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 256},
+			want:    1,
+			wantErr: false,
+		},
+		{
+			name:    "different length and content",
+			a:       &Capdu{Cla: 0x00, Ins: 0xD6, P1: 0x00, P2: 0x00, Data: []byte{0x01, 0x02, 0x03}},
+			b:       &Capdu{Cla: 0x00, Ins: 0xD6, P1: 0x00, P2: 0x00, Data: []byte{0x01}},
+			want:    3,
+			wantErr: false,
+		},
+		{
+			name:    "error: first Capdu invalid",
+			a:       &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 65537},
+			b:       &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00},
+			want:    0,
+			wantErr: true,
+		},
+		{
+			name:    "error: second Capdu invalid",
+			a:       &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00},
+			b:       &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 65537},
+			want:    0,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CapduByteDistance(tt.a, tt.b)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CapduByteDistance() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if got != tt.want {
+				t.Errorf("CapduByteDistance() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtendedLengthStats(t *testing.T) {
+	tests := []struct {
+		name         string
+		cmds         []*Capdu
+		wantTotal    int
+		wantExtended int
+	}{
+		{
+			name:         "empty",
+			cmds:         nil,
+			wantTotal:    0,
+			wantExtended: 0,
+		},
+		{
+			name: "mixed",
+			cmds: []*Capdu{
+				{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00},
+				{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 257},
+				{Cla: 0x00, Ins: 0xD6, P1: 0x00, P2: 0x00, Data: make([]byte, 256)},
+				{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 256},
+				nil,
+			},
+			wantTotal:    4,
+			wantExtended: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotTotal, gotExtended := ExtendedLengthStats(tt.cmds)
+			if gotTotal != tt.wantTotal {
+				t.Errorf("ExtendedLengthStats() gotTotal = %v, want %v", gotTotal, tt.wantTotal)
+			}
+			if gotExtended != tt.wantExtended {
+				t.Errorf("ExtendedLengthStats() gotExtended = %v, want %v", gotExtended, tt.wantExtended)
+			}
+		})
+	}
+}
+
+func TestCapdu_String(t *testing.T) {
+	type fields struct {
+		Cla  byte
+		Ins  byte
+		P1   byte
+		P2   byte
+		Data []byte
+		Ne   int
+	}
+
+	tests := []struct {
+		name    string
+		fields  fields
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "to string",
+			fields:  fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Data: []byte{0x01, 0x02}, Ne: 3},
+			want:    "00A4040102010203",
+			wantErr: false,
+		},
+		{
+			name:    "error: invalid ne",
+			fields:  fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Data: []byte{0x01, 0x02}, Ne: 65537},
+			want:    "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Capdu{
+				Cla:  tt.fields.Cla,
+				Ins:  tt.fields.Ins,
+				P1:   tt.fields.P1,
+				P2:   tt.fields.P2,
+				Data: tt.fields.Data,
+				Ne:   tt.fields.Ne,
+			}
+			got, err := c.String()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("String() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+			if got != tt.want {
+				t.Errorf("String() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapdu_MaxResponseBytes(t *testing.T) {
+	tests := []struct {
+		name string
+		ne   int
+		want int
+	}{
+		{name: "no response data", ne: 0, want: 0},
+		{name: "standard maximum", ne: 256, want: 256},
+		{name: "extended maximum", ne: 65536, want: 65536},
+		{name: "negative", ne: -1, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: tt.ne}
+			if got := c.MaxResponseBytes(); got != tt.want {
+				t.Errorf("MaxResponseBytes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDescribeNe(t *testing.T) {
+	tests := []struct {
+		name string
+		ne   int
+		want string
+	}{
+		{name: "zero", ne: 0, want: "no response expected"},
+		{name: "negative", ne: -1, want: "invalid Ne -1"},
+		{name: "one", ne: 1, want: "expect up to 1 bytes (standard, Le=01)"},
+		{name: "standard max", ne: 256, want: "expect up to 256 bytes (standard, Le=00)"},
+		{name: "extended min", ne: 257, want: "expect up to 257 bytes (extended, Le=0101)"},
+		{name: "extended max", ne: 65536, want: "expect up to 65536 bytes (extended, Le=0000)"},
+		{name: "invalid", ne: 65537, want: "invalid Ne 65537 (exceeds maximum of 65536)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DescribeNe(tt.ne); got != tt.want {
+				t.Errorf("DescribeNe() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapdu_MaxResponseFrameSize(t *testing.T) {
+	tests := []struct {
+		name string
+		ne   int
+		want int
+	}{
+		{name: "no response data", ne: 0, want: 2},
+		{name: "negative Ne", ne: -1, want: 2},
+		{name: "one byte", ne: 1, want: 3},
+		{name: "standard max", ne: 256, want: 258},
+		{name: "extended", ne: 257, want: 259},
+		{name: "extended max", ne: 65536, want: 65538},
+		{name: "capped", ne: 70000, want: MaxLenRapdu},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Capdu{Cla: 0x00, Ins: 0xB0, Ne: tt.ne}
+			if got := c.MaxResponseFrameSize(); got != tt.want {
+				t.Errorf("MaxResponseFrameSize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapdu_Case(t *testing.T) {
+	tests := []struct {
+		name  string
+		capdu *Capdu
+		want  int
+	}{
+		{name: "Case 1", capdu: &Capdu{Cla: 0x00, Ins: 0x44}, want: 1},
+		{name: "Case 1 with empty Data", capdu: &Capdu{Cla: 0x00, Ins: 0x44, Data: []byte{}}, want: 1},
+		{name: "Case 1 with negative Ne", capdu: &Capdu{Cla: 0x00, Ins: 0x44, Ne: -1}, want: 1},
+		{name: "Case 2", capdu: &Capdu{Cla: 0x00, Ins: 0xB0, Ne: 256}, want: 2},
+		{name: "Case 3", capdu: &Capdu{Cla: 0x00, Ins: 0xD6, Data: []byte{0x01}}, want: 3},
+		{name: "Case 3 with negative Ne", capdu: &Capdu{Cla: 0x00, Ins: 0xD6, Data: []byte{0x01}, Ne: -1}, want: 3},
+		{name: "Case 4", capdu: &Capdu{Cla: 0x00, Ins: 0xA4, Data: []byte{0x01}, Ne: 256}, want: 4},
+		{name: "Case 4 extended", capdu: &Capdu{Cla: 0x00, Ins: 0xA4, Data: make([]byte, 300), Ne: 65536}, want: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.capdu.Case(); got != tt.want {
+				t.Errorf("Case() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapdu_AssertCase(t *testing.T) {
+	tests := []struct {
+		name     string
+		capdu    *Capdu
+		expected int
+		wantErr  string
+	}{
+		{
+			name:     "match",
+			capdu:    &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0xA0, 0x00}, Ne: 256},
+			expected: 4,
+			wantErr:  "",
+		},
+		{
+			name:     "mismatch",
+			capdu:    &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0xA0, 0x00}},
+			expected: 4,
+			wantErr:  "skythen/apdu: expected Case 4 command, got Case 3",
+		},
+		{
+			name:     "invalid expectation",
+			capdu:    &Capdu{Cla: 0x00, Ins: 0x44},
+			expected: 5,
+			wantErr:  "skythen/apdu: expected Case 5 command, got Case 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.capdu.AssertCase(tt.expected)
+
+			gotErr := ""
+			if err != nil {
+				gotErr = err.Error()
+			}
+
+			if gotErr != tt.wantErr {
+				t.Errorf("AssertCase() error = %q, want %q", gotErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCapdu_ExpectsData(t *testing.T) {
+	tests := []struct {
+		name  string
+		capdu Capdu
+		want  bool
+	}{
+		{
+			name:  "Case 1",
+			capdu: Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00},
+			want:  false,
+		},
+		{
+			name:  "Case 2",
+			capdu: Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Ne: 256},
+			want:  true,
+		},
+		{
+			name:  "Case 3",
+			capdu: Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0x01, 0x02}},
+			want:  false,
+		},
+		{
+			name:  "Case 4",
+			capdu: Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0x01, 0x02}, Ne: 1},
+			want:  true,
+		},
+		{
+			name:  "negative Ne",
+			capdu: Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Ne: -1},
+			want:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.capdu.ExpectsData(); got != tt.want {
+				t.Errorf("ExpectsData() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapdu_EmitsLe(t *testing.T) {
+	tests := []struct {
+		name  string
+		capdu Capdu
+		want  bool
+	}{
+		{
+			name:  "Case 1",
+			capdu: Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00},
+			want:  false,
+		},
+		{
+			name:  "Case 2",
+			capdu: Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Ne: 256},
+			want:  true,
+		},
+		{
+			name:  "Case 3",
+			capdu: Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0x01, 0x02}},
+			want:  false,
+		},
+		{
+			name:  "Case 4",
+			capdu: Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0x01, 0x02}, Ne: 1},
+			want:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.capdu.EmitsLe(); got != tt.want {
+				t.Errorf("EmitsLe() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapdu_LcBytes(t *testing.T) {
+	tests := []struct {
+		name  string
+		capdu Capdu
+		want  []byte
+	}{
+		{
+			name:  "no data",
+			capdu: Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 256},
+			want:  nil,
+		},
+		{
+			name:  "standard",
+			capdu: Capdu{Cla: 0x00, Ins: 0xDA, P1: 0x00, P2: 0x00, Data: make([]byte, 1)},
+			want:  []byte{0x01},
+		},
+		{
+			name:  "standard max",
+			capdu: Capdu{Cla: 0x00, Ins: 0xDA, P1: 0x00, P2: 0x00, Data: make([]byte, 255)},
+			want:  []byte{0xFF},
+		},
+		{
+			name:  "extended",
+			capdu: Capdu{Cla: 0x00, Ins: 0xDA, P1: 0x00, P2: 0x00, Data: make([]byte, 256)},
+			want:  []byte{0x00, 0x01, 0x00},
+		},
+		{
+			name:  "extended max",
+			capdu: Capdu{Cla: 0x00, Ins: 0xDA, P1: 0x00, P2: 0x00, Data: make([]byte, 65535)},
+			want:  []byte{0x00, 0xFF, 0xFF},
+		},
+		{
+			name:  "extended because of Ne",
+			capdu: Capdu{Cla: 0x00, Ins: 0xDA, P1: 0x00, P2: 0x00, Data: make([]byte, 2), Ne: 257},
+			want:  []byte{0x00, 0x00, 0x02},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.capdu.LcBytes()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LcBytes() got = %X, want %X", got, tt.want)
+			}
+
+			b, err := tt.capdu.Bytes()
+			if err != nil {
+				t.Fatalf("Bytes() error = %v", err)
+			}
+
+			if len(got) > 0 && !reflect.DeepEqual(b[LenHeader:LenHeader+len(got)], got) {
+				t.Errorf("LcBytes() got = %X does not match Bytes() %X", got, b[:LenHeader+len(got)])
+			}
+		})
+	}
+}
+
+func TestCapdu_LeBytes(t *testing.T) {
+	tests := []struct {
+		name    string
+		capdu   Capdu
+		want    []byte
+		wantErr bool
+	}{
+		{
+			name:    "Case 1",
+			capdu:   Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00},
+			want:    nil,
+			wantErr: false,
+		},
+		{
+			name:    "Case 2 standard",
+			capdu:   Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 10},
+			want:    []byte{0x0A},
+			wantErr: false,
+		},
+		{
+			name:    "Case 2 standard max",
+			capdu:   Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 256},
+			want:    []byte{0x00},
+			wantErr: false,
+		},
+		{
+			name:    "Case 2 extended",
+			capdu:   Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 257},
+			want:    []byte{0x00, 0x01, 0x01},
+			wantErr: false,
+		},
+		{
+			name:    "Case 2 extended max",
+			capdu:   Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 65536},
+			want:    []byte{0x00, 0x00, 0x00},
+			wantErr: false,
+		},
+		{
+			name:    "Case 3",
+			capdu:   Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0x01, 0x02}},
+			want:    nil,
+			wantErr: false,
+		},
+		{
+			name:    "Case 4 standard",
+			capdu:   Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0x01, 0x02}, Ne: 256},
+			want:    []byte{0x00},
+			wantErr: false,
+		},
+		{
+			name:    "Case 4 extended Ne",
+			capdu:   Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0x01, 0x02}, Ne: 0x1234},
+			want:    []byte{0x12, 0x34},
+			wantErr: false,
+		},
+		{
+			name:    "Case 4 extended data, max Ne",
+			capdu:   Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: make([]byte, 256), Ne: 65536},
+			want:    []byte{0x00, 0x00},
+			wantErr: false,
+		},
+		{
+			name:    "error: Ne exceeds maximum",
+			capdu:   Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 65537},
 			want:    nil,
 			wantErr: true,
 		},
 	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.capdu.LeBytes()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LeBytes() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LeBytes() got = %X, want %X", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapdu_Bytes_NeRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		capdu     *Capdu
+		wantBytes []byte
+	}{
+		{
+			name:      "Ne 0 is Case 1 without Le",
+			capdu:     &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 0},
+			wantBytes: []byte{0x00, 0xB0, 0x00, 0x00},
+		},
+		{
+			name:      "Ne 0 with data is Case 3 without Le",
+			capdu:     &Capdu{Cla: 0x00, Ins: 0xD6, P1: 0x00, P2: 0x00, Data: []byte{0x01}, Ne: 0},
+			wantBytes: []byte{0x00, 0xD6, 0x00, 0x00, 0x01, 0x01},
+		},
+		{
+			name:      "Ne 256 is Case 2 with Le 00",
+			capdu:     &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 256},
+			wantBytes: []byte{0x00, 0xB0, 0x00, 0x00, 0x00},
+		},
+		{
+			name:      "Ne 256 with data is Case 4 with Le 00",
+			capdu:     &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0xA0}, Ne: 256},
+			wantBytes: []byte{0x00, 0xA4, 0x04, 0x00, 0x01, 0xA0, 0x00},
+		},
+		{
+			name:      "Ne 65536 is extended Case 2 with Le 0000",
+			capdu:     &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 65536},
+			wantBytes: []byte{0x00, 0xB0, 0x00, 0x00, 0x00, 0x00, 0x00},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := tt.capdu.Bytes()
+			if err != nil {
+				t.Fatalf("Bytes() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(b, tt.wantBytes) {
+				t.Errorf("Bytes() got = %X, want %X", b, tt.wantBytes)
+			}
+
+			got, err := ParseCapdu(b)
+			if err != nil {
+				t.Fatalf("ParseCapdu() error = %v", err)
+			}
+
+			if got.Ne != tt.capdu.Ne {
+				t.Errorf("ParseCapdu() got Ne = %d, want %d", got.Ne, tt.capdu.Ne)
+			}
+		})
+	}
+}
+
+func TestCapdu_Bytes_Case3WithoutLe(t *testing.T) {
+	for _, dataLen := range []int{1, MaxLenCommandDataStandard, MaxLenCommandDataStandard + 1, MaxLenCommandDataExtended} {
+		c := &Capdu{Cla: 0x00, Ins: 0xDA, P1: 0x01, P2: 0x02, Data: make([]byte, dataLen)}
+
+		lenLc := LenLCStandard
+		if dataLen > MaxLenCommandDataStandard {
+			lenLc = LenLCExtended
+		}
+
+		b, err := c.Bytes()
+		if err != nil {
+			t.Fatalf("Bytes() error = %v", err)
+		}
+
+		if len(b) != LenHeader+lenLc+dataLen {
+			t.Errorf("Bytes() len = %d, want %d for data length %d", len(b), LenHeader+lenLc+dataLen, dataLen)
+		}
+	}
+}
+
+func TestCapdu_SFI(t *testing.T) {
+	type fields struct {
+		Cla byte
+		Ins byte
+		P1  byte
+		P2  byte
+	}
+
+	tests := []struct {
+		name   string
+		fields fields
+		want   byte
+		want1  bool
+	}{
+		{
+			name:   "READ RECORD SFI 1",
+			fields: fields{Cla: 0x00, Ins: 0xB2, P1: 0x01, P2: 0x0C},
+			want:   0x01,
+			want1:  true,
+		},
+		{
+			name:   "UPDATE RECORD SFI 30",
+			fields: fields{Cla: 0x00, Ins: 0xDC, P1: 0x01, P2: 0xF4},
+			want:   0x1E,
+			want1:  true,
+		},
+		{
+			name:   "READ RECORD currently selected EF",
+			fields: fields{Cla: 0x00, Ins: 0xB2, P1: 0x01, P2: 0x04},
+			want:   0x00,
+			want1:  false,
+		},
+		{
+			name:   "READ RECORD RFU SFI",
+			fields: fields{Cla: 0x00, Ins: 0xB2, P1: 0x01, P2: 0xFC},
+			want:   0x00,
+			want1:  false,
+		},
+		{
+			name:   "SELECT",
+			fields: fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x0C},
+			want:   0x00,
+			want1:  false,
+		},
+		{
+			name:   "proprietary class",
+			fields: fields{Cla: 0x80, Ins: 0xE2, P1: 0x80, P2: 0x0C},
+			want:   0x00,
+			want1:  false,
+		},
+		{
+			name:   "RFU class",
+			fields: fields{Cla: 0x20, Ins: 0xB2, P1: 0x01, P2: 0x0C},
+			want:   0x00,
+			want1:  false,
+		},
+		{
+			name:   "invalid class",
+			fields: fields{Cla: 0xFF, Ins: 0xB2, P1: 0x01, P2: 0x0C},
+			want:   0x00,
+			want1:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Capdu{
+				Cla: tt.fields.Cla,
+				Ins: tt.fields.Ins,
+				P1:  tt.fields.P1,
+				P2:  tt.fields.P2,
+			}
+			got, got1 := c.SFI()
+			if got != tt.want {
+				t.Errorf("SFI() got = %v, want %v", got, tt.want)
+			}
+			if got1 != tt.want1 {
+				t.Errorf("SFI() got1 = %v, want %v", got1, tt.want1)
+			}
+		})
+	}
+}
+
+func TestCapdu_HeaderUint32(t *testing.T) {
+	tests := []struct {
+		name  string
+		capdu *Capdu
+		want  uint32
+	}{
+		{
+			name:  "SELECT",
+			capdu: &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00},
+			want:  0x00A40400,
+		},
+		{
+			name:  "GET STATUS",
+			capdu: &Capdu{Cla: 0x80, Ins: 0xF2, P1: 0xE0, P2: 0x02, Data: []byte{0x4F, 0x00}, Ne: 256},
+			want:  0x80F2E002,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.capdu.HeaderUint32()
+			if got != tt.want {
+				t.Errorf("HeaderUint32() = %08X, want %08X", got, tt.want)
+			}
+
+			if rt := CapduFromHeaderUint32(got, tt.capdu.Data, tt.capdu.Ne); !reflect.DeepEqual(rt, tt.capdu) {
+				t.Errorf("CapduFromHeaderUint32() = %v, want %v", rt, tt.capdu)
+			}
+		})
+	}
+}
+
+func TestCapdu_HeaderBytes_BodyBytes(t *testing.T) {
+	tests := []struct {
+		name     string
+		capdu    Capdu
+		wantBody []byte
+		wantErr  bool
+	}{
+		{
+			name:     "Case 1",
+			capdu:    Capdu{Cla: 0x00, Ins: 0x70, P1: 0x80, P2: 0x01},
+			wantBody: []byte{},
+			wantErr:  false,
+		},
+		{
+			name:     "Case 2 extended",
+			capdu:    Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 65536},
+			wantBody: []byte{0x00, 0x00, 0x00},
+			wantErr:  false,
+		},
+		{
+			name:     "Case 3",
+			capdu:    Capdu{Cla: 0x00, Ins: 0xD6, P1: 0x00, P2: 0x10, Data: []byte{0x01, 0x02}},
+			wantBody: []byte{0x02, 0x01, 0x02},
+			wantErr:  false,
+		},
+		{
+			name:     "Case 4",
+			capdu:    Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0xA0, 0x00}, Ne: 256},
+			wantBody: []byte{0x02, 0xA0, 0x00, 0x00},
+			wantErr:  false,
+		},
+		{
+			name:     "error: Ne exceeds maximum",
+			capdu:    Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 65537},
+			wantBody: nil,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := tt.capdu.HeaderBytes()
+			if !reflect.DeepEqual(header, []byte{tt.capdu.Cla, tt.capdu.Ins, tt.capdu.P1, tt.capdu.P2}) {
+				t.Errorf("HeaderBytes() got = %X", header)
+			}
+
+			body, err := tt.capdu.BodyBytes()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("BodyBytes() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if !reflect.DeepEqual(body, tt.wantBody) {
+				t.Errorf("BodyBytes() got = %X, want %X", body, tt.wantBody)
+			}
+
+			b, err := tt.capdu.Bytes()
+			if err != nil {
+				t.Fatalf("Bytes() error = %v", err)
+			}
+
+			if joined := append(header, body...); !reflect.DeepEqual(joined, b) {
+				t.Errorf("HeaderBytes()+BodyBytes() got = %X, want %X", joined, b)
+			}
+
+			parsed, err := ParseCapduParts(header, body)
+			if err != nil {
+				t.Fatalf("ParseCapduParts() error = %v", err)
+			}
+
+			if !parsed.Equal(&tt.capdu) {
+				t.Errorf("ParseCapduParts() got = %v, want %v", parsed, tt.capdu)
+			}
+		})
+	}
+}
+
+func TestCapdu_ShapeKey(t *testing.T) {
+	tests := []struct {
+		name  string
+		capdu *Capdu
+		want  string
+	}{
+		{
+			name:  "Case 1",
+			capdu: &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00},
+			want:  "00A40400/0/0",
+		},
+		{
+			name:  "Case 4",
+			capdu: &Capdu{Cla: 0x80, Ins: 0xF2, P1: 0xE0, P2: 0x02, Data: []byte{0x4F, 0x00}, Ne: 256},
+			want:  "80F2E002/2/256",
+		},
+		{
+			name:  "Case 4 extended",
+			capdu: &Capdu{Cla: 0x00, Ins: 0xDA, P1: 0x01, P2: 0x02, Data: make([]byte, 300), Ne: 65536},
+			want:  "00DA0102/300/65536",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.capdu.ShapeKey(); got != tt.want {
+				t.Errorf("ShapeKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapdu_ShapeKey_IgnoresData(t *testing.T) {
+	a := &Capdu{Cla: 0x00, Ins: 0x20, P1: 0x00, P2: 0x80, Data: []byte{0x31, 0x32, 0x33, 0x34}}
+	b := &Capdu{Cla: 0x00, Ins: 0x20, P1: 0x00, P2: 0x80, Data: []byte{0x35, 0x36, 0x37, 0x38}}
+
+	if a.ShapeKey() != b.ShapeKey() {
+		t.Errorf("ShapeKey() = %v and %v, want equal keys", a.ShapeKey(), b.ShapeKey())
+	}
+}
+
+func TestCapdu_GoLiteral(t *testing.T) {
+	tests := []struct {
+		name    string
+		capdu   Capdu
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "Case 1",
+			capdu:   Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00},
+			want:    "[]byte{0x00, 0xA4, 0x04, 0x00}",
+			wantErr: false,
+		},
+		{
+			name:    "Case 4",
+			capdu:   Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0xA0, 0x00}, Ne: 256},
+			want:    "[]byte{0x00, 0xA4, 0x04, 0x00, 0x02, 0xA0, 0x00, 0x00}",
+			wantErr: false,
+		},
+		{
+			name:    "error: invalid ne",
+			capdu:   Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Ne: 65537},
+			want:    "",
+			wantErr: true,
+		},
+	}
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			c := &Capdu{
-				Cla:  tt.fields.Cla,
-				Ins:  tt.fields.Ins,
-				P1:   tt.fields.P1,
-				P2:   tt.fields.P2,
-				Data: tt.fields.Data,
-				Ne:   tt.fields.Ne,
-			}
-			got, err := c.Bytes()
+			got, err := tt.capdu.GoLiteral()
 			if (err != nil) != tt.wantErr {
-				t.Errorf("Bytes() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("GoLiteral() error = %v, wantErr %v", err, tt.wantErr)
 
 				return
 			}
-			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("Bytes() got = %v, want %v", got, tt.want)
+			if got != tt.want {
+				t.Errorf("GoLiteral() got = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
-func TestCapdu_IsExtendedLength(t *testing.T) {
-	extendedData := make([]byte, 256)
-	for i := range extendedData {
-		extendedData[i] = 0xFF
+func TestCapdu_SelectedAID(t *testing.T) {
+	aid := []byte{0xA0, 0x00, 0x00, 0x01, 0x51, 0x00, 0x00}
+
+	tests := []struct {
+		name  string
+		capdu *Capdu
+		want  []byte
+		want1 bool
+	}{
+		{
+			name:  "select by AID",
+			capdu: &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: aid, Ne: 256},
+			want:  aid,
+			want1: true,
+		},
+		{
+			name:  "select next by AID",
+			capdu: &Capdu{Cla: 0x01, Ins: 0xA4, P1: 0x04, P2: 0x02, Data: aid, Ne: 256},
+			want:  aid,
+			want1: true,
+		},
+		{
+			name:  "select by file identifier",
+			capdu: &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x00, P2: 0x0C, Data: []byte{0x3F, 0x00}},
+			want:  nil,
+			want1: false,
+		},
+		{
+			name:  "select by path",
+			capdu: &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x08, P2: 0x0C, Data: []byte{0x3F, 0x00, 0x2F, 0x00}},
+			want:  nil,
+			want1: false,
+		},
+		{
+			name:  "select by AID without data",
+			capdu: &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Ne: 256},
+			want:  nil,
+			want1: false,
+		},
+		{
+			name:  "not a SELECT",
+			capdu: &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x04, P2: 0x00, Data: aid},
+			want:  nil,
+			want1: false,
+		},
 	}
 
-	standardData := make([]byte, 255)
-	for i := range standardData {
-		standardData[i] = 0xFF
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, got1 := tt.capdu.SelectedAID()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SelectedAID() got = %X, want %X", got, tt.want)
+			}
+			if got1 != tt.want1 {
+				t.Errorf("SelectedAID() got1 = %v, want %v", got1, tt.want1)
+			}
+		})
+	}
+}
+
+func TestRapdu_Bytes(t *testing.T) {
+	tooExtendedData := make([]byte, MaxLenResponseDataExtended+1)
+	for i := range tooExtendedData {
+		tooExtendedData[i] = 0xFF
 	}
 
 	type fields struct {
-		Cla  byte
-		Ins  byte
-		P1   byte
-		P2   byte
 		Data []byte
-		Ne   int
+		SW1  byte
+		SW2  byte
 	}
 
 	tests := []struct {
-		name   string
-		fields fields
-		want   bool
+		name    string
+		fields  fields
+		want    []byte
+		wantErr bool
 	}{
 		{
-			name:   "extended length ne",
-			fields: fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Ne: 257},
-			want:   true,
+			name:    "only SW",
+			fields:  fields{Data: nil, SW1: 0x6A, SW2: 0x80},
+			want:    []byte{0x6A, 0x80},
+			wantErr: false,
 		},
 		{
-			name:   "extended length data",
-			fields: fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Data: extendedData, Ne: 256},
-			want:   true,
+			name:    "data and SW",
+			fields:  fields{Data: []byte{0x01, 0x02, 0x03}, SW1: 0x90, SW2: 0x00},
+			want:    []byte{0x01, 0x02, 0x03, 0x90, 0x00},
+			wantErr: false,
 		},
 		{
-			name:   "standard length",
-			fields: fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Data: standardData, Ne: 256},
-			want:   false,
+			name:    "error: data exceeds maximum length",
+			fields:  fields{Data: tooExtendedData, SW1: 0x90, SW2: 0x00},
+			want:    nil,
+			wantErr: true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			c := &Capdu{
-				Cla:  tt.fields.Cla,
-				Ins:  tt.fields.Ins,
-				P1:   tt.fields.P1,
-				P2:   tt.fields.P2,
+			r := &Rapdu{
 				Data: tt.fields.Data,
-				Ne:   tt.fields.Ne,
+				SW1:  tt.fields.SW1,
+				SW2:  tt.fields.SW2,
 			}
-			if got := c.IsExtendedLength(); got != tt.want {
-				t.Errorf("IsExtendedLength() = %v, want %v", got, tt.want)
+			got, err := r.Bytes()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Bytes() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Bytes() got = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
-func TestCapdu_String(t *testing.T) {
+func TestRapdu_String(t *testing.T) {
 	type fields struct {
-		Cla  byte
-		Ins  byte
-		P1   byte
-		P2   byte
 		Data []byte
-		Ne   int
+		SW1  byte
+		SW2  byte
 	}
 
 	tests := []struct {
@@ -491,14 +3166,14 @@ func TestCapdu_String(t *testing.T) {
 		wantErr bool
 	}{
 		{
-			name:    "to string",
-			fields:  fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Data: []byte{0x01, 0x02}, Ne: 3},
-			want:    "00A4040102010203",
+			name:    "trailer only",
+			fields:  fields{Data: []byte{0x01, 0x02, 0x03}, SW1: 0x90, SW2: 0x00},
+			want:    "0102039000",
 			wantErr: false,
 		},
 		{
-			name:    "error: invalid ne",
-			fields:  fields{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x01, Data: []byte{0x01, 0x02}, Ne: 65537},
+			name:    "error: invalid length",
+			fields:  fields{Data: make([]byte, 65537), SW1: 0x90, SW2: 0x00},
 			want:    "",
 			wantErr: true,
 		},
@@ -506,15 +3181,12 @@ func TestCapdu_String(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			c := &Capdu{
-				Cla:  tt.fields.Cla,
-				Ins:  tt.fields.Ins,
-				P1:   tt.fields.P1,
-				P2:   tt.fields.P2,
+			r := &Rapdu{
 				Data: tt.fields.Data,
-				Ne:   tt.fields.Ne,
+				SW1:  tt.fields.SW1,
+				SW2:  tt.fields.SW2,
 			}
-			got, err := c.String()
+			got, err := r.String()
 			if (err != nil) != tt.wantErr {
 				t.Errorf("String() error = %v, wantErr %v", err, tt.wantErr)
 
@@ -527,106 +3199,308 @@ func TestCapdu_String(t *testing.T) {
 	}
 }
 
-func TestRapdu_Bytes(t *testing.T) {
-	tooExtendedData := make([]byte, MaxLenResponseDataExtended+1)
-	for i := range tooExtendedData {
-		tooExtendedData[i] = 0xFF
+func TestRapdu_TruncateToNe(t *testing.T) {
+	tests := []struct {
+		name  string
+		rapdu *Rapdu
+		ne    int
+		want  *Rapdu
+	}{
+		{
+			name:  "data exceeds Ne",
+			rapdu: &Rapdu{Data: []byte{0x01, 0x02, 0x03, 0x04}, SW1: 0x90, SW2: 0x00},
+			ne:    2,
+			want:  &Rapdu{Data: []byte{0x01, 0x02}, SW1: 0x90, SW2: 0x00},
+		},
+		{
+			name:  "data within Ne",
+			rapdu: &Rapdu{Data: []byte{0x01, 0x02}, SW1: 0x62, SW2: 0x82},
+			ne:    256,
+			want:  &Rapdu{Data: []byte{0x01, 0x02}, SW1: 0x62, SW2: 0x82},
+		},
+		{
+			name:  "no data expected",
+			rapdu: &Rapdu{Data: []byte{0x01, 0x02}, SW1: 0x90, SW2: 0x00},
+			ne:    0,
+			want:  &Rapdu{SW1: 0x90, SW2: 0x00},
+		},
+		{
+			name:  "Ne exceeds maximum",
+			rapdu: &Rapdu{Data: make([]byte, 65537), SW1: 0x90, SW2: 0x00},
+			ne:    70000,
+			want:  &Rapdu{Data: make([]byte, 65536), SW1: 0x90, SW2: 0x00},
+		},
 	}
 
-	type fields struct {
-		Data []byte
-		SW1  byte
-		SW2  byte
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := &Rapdu{Data: append([]byte(nil), tt.rapdu.Data...), SW1: tt.rapdu.SW1, SW2: tt.rapdu.SW2}
+
+			got := tt.rapdu.TruncateToNe(tt.ne)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TruncateToNe() = %v, want %v", got, tt.want)
+			}
+
+			if !reflect.DeepEqual(tt.rapdu, orig) {
+				t.Errorf("TruncateToNe() modified original Rapdu")
+			}
+		})
 	}
+}
 
+func TestRapdu_GoLiteral(t *testing.T) {
 	tests := []struct {
 		name    string
-		fields  fields
-		want    []byte
+		rapdu   Rapdu
+		want    string
 		wantErr bool
 	}{
 		{
 			name:    "only SW",
-			fields:  fields{Data: nil, SW1: 0x6A, SW2: 0x80},
-			want:    []byte{0x6A, 0x80},
+			rapdu:   Rapdu{SW1: 0x90, SW2: 0x00},
+			want:    "[]byte{0x90, 0x00}",
 			wantErr: false,
 		},
 		{
 			name:    "data and SW",
-			fields:  fields{Data: []byte{0x01, 0x02, 0x03}, SW1: 0x90, SW2: 0x00},
-			want:    []byte{0x01, 0x02, 0x03, 0x90, 0x00},
+			rapdu:   Rapdu{Data: []byte{0x01, 0xAB}, SW1: 0x61, SW2: 0x0F},
+			want:    "[]byte{0x01, 0xAB, 0x61, 0x0F}",
 			wantErr: false,
 		},
 		{
-			name:    "data and SW, truncate data",
-			fields:  fields{Data: tooExtendedData, SW1: 0x90, SW2: 0x00},
-			want:    nil,
-			wantErr: true,
+			name:    "error: invalid length",
+			rapdu:   Rapdu{Data: make([]byte, 65537), SW1: 0x90, SW2: 0x00},
+			want:    "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.rapdu.GoLiteral()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GoLiteral() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+			if got != tt.want {
+				t.Errorf("GoLiteral() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRapdu_DataEqualsMasked(t *testing.T) {
+	type args struct {
+		expected []byte
+		mask     []byte
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		args args
+		want bool
+	}{
+		{
+			name: "full mask equal",
+			data: []byte{0x01, 0x02, 0x03},
+			args: args{expected: []byte{0x01, 0x02, 0x03}, mask: []byte{0xFF, 0xFF, 0xFF}},
+			want: true,
+		},
+		{
+			name: "full mask not equal",
+			data: []byte{0x01, 0x02, 0x03},
+			args: args{expected: []byte{0x01, 0x02, 0x04}, mask: []byte{0xFF, 0xFF, 0xFF}},
+			want: false,
+		},
+		{
+			name: "partial mask ignores volatile byte",
+			data: []byte{0x01, 0x7F, 0x03},
+			args: args{expected: []byte{0x01, 0x00, 0x03}, mask: []byte{0xFF, 0x00, 0xFF}},
+			want: true,
+		},
+		{
+			name: "partial mask compares fixed byte",
+			data: []byte{0x02, 0x7F, 0x03},
+			args: args{expected: []byte{0x01, 0x00, 0x03}, mask: []byte{0xFF, 0x00, 0xFF}},
+			want: false,
+		},
+		{
+			name: "empty",
+			data: nil,
+			args: args{expected: []byte{}, mask: []byte{}},
+			want: true,
+		},
+		{
+			name: "length mismatch expected",
+			data: []byte{0x01, 0x02},
+			args: args{expected: []byte{0x01}, mask: []byte{0xFF}},
+			want: false,
+		},
+		{
+			name: "length mismatch mask",
+			data: []byte{0x01, 0x02},
+			args: args{expected: []byte{0x01, 0x02}, mask: []byte{0xFF}},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Rapdu{Data: tt.data, SW1: 0x90, SW2: 0x00}
+			if got := r.DataEqualsMasked(tt.args.expected, tt.args.mask); got != tt.want {
+				t.Errorf("DataEqualsMasked() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRapdu_LooksLikeTLV(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want bool
+	}{
+		{
+			name: "FCI template",
+			data: []byte{0x6F, 0x07, 0x84, 0x05, 0xA0, 0x00, 0x00, 0x01, 0x51},
+			want: true,
+		},
+		{
+			name: "two-byte tag with long form length",
+			data: append([]byte{0x9F, 0x7F, 0x81, 0x80}, make([]byte, 128)...),
+			want: true,
+		},
+		{
+			name: "sequence of data objects",
+			data: []byte{0x5A, 0x01, 0x01, 0x5F, 0x20, 0x00},
+			want: true,
+		},
+		{
+			name: "empty",
+			data: nil,
+			want: false,
+		},
+		{
+			name: "ASCII text",
+			data: []byte("Hello"),
+			want: false,
+		},
+		{
+			name: "padding",
+			data: []byte{0x00, 0x00, 0x00},
+			want: false,
+		},
+		{
+			name: "length exceeds data",
+			data: []byte{0x6F, 0x10, 0x84},
+			want: false,
+		},
+		{
+			name: "invalid length field",
+			data: []byte{0x6F, 0x85, 0x01, 0x02, 0x03, 0x04, 0x05},
+			want: false,
+		},
+		{
+			name: "truncated tag",
+			data: []byte{0x9F},
+			want: false,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			r := &Rapdu{
-				Data: tt.fields.Data,
-				SW1:  tt.fields.SW1,
-				SW2:  tt.fields.SW2,
-			}
-			got, err := r.Bytes()
-			if (err != nil) != tt.wantErr {
-				t.Errorf("Bytes() error = %v, wantErr %v", err, tt.wantErr)
-
-				return
-			}
-			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("Bytes() got = %v, want %v", got, tt.want)
+			r := &Rapdu{Data: tt.data, SW1: 0x90, SW2: 0x00}
+			if got := r.LooksLikeTLV(); got != tt.want {
+				t.Errorf("LooksLikeTLV() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
-func TestRapdu_String(t *testing.T) {
-	type fields struct {
-		Data []byte
-		SW1  byte
-		SW2  byte
-	}
-
+func TestRapdu_IsTruncatedTLV(t *testing.T) {
 	tests := []struct {
 		name    string
-		fields  fields
-		want    string
+		data    []byte
+		want    bool
 		wantErr bool
 	}{
 		{
-			name:    "trailer only",
-			fields:  fields{Data: []byte{0x01, 0x02, 0x03}, SW1: 0x90, SW2: 0x00},
-			want:    "0102039000",
+			name:    "complete",
+			data:    []byte{0x6F, 0x03, 0x84, 0x01, 0xA0},
+			want:    false,
 			wantErr: false,
 		},
 		{
-			name:    "error: invalid length",
-			fields:  fields{Data: make([]byte, 65537), SW1: 0x90, SW2: 0x00},
-			want:    "",
+			name:    "complete with trailing data",
+			data:    []byte{0x6F, 0x01, 0x84, 0x01, 0xA0},
+			want:    false,
+			wantErr: false,
+		},
+		{
+			name:    "truncated",
+			data:    []byte{0x6F, 0x05, 0x84, 0x01, 0xA0},
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "truncated long form length",
+			data:    append([]byte{0x70, 0x82, 0x01, 0x00}, make([]byte, 200)...),
+			want:    true,
+			wantErr: false,
+		},
+		{
+			name:    "error: empty data",
+			data:    nil,
+			want:    false,
+			wantErr: true,
+		},
+		{
+			name:    "error: malformed length",
+			data:    []byte{0x6F, 0x85, 0x01},
+			want:    false,
 			wantErr: true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			r := &Rapdu{
-				Data: tt.fields.Data,
-				SW1:  tt.fields.SW1,
-				SW2:  tt.fields.SW2,
-			}
-			got, err := r.String()
+			r := &Rapdu{Data: tt.data, SW1: 0x90, SW2: 0x00}
+			got, err := r.IsTruncatedTLV()
 			if (err != nil) != tt.wantErr {
-				t.Errorf("String() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("IsTruncatedTLV() error = %v, wantErr %v", err, tt.wantErr)
 
 				return
 			}
 			if got != tt.want {
-				t.Errorf("String() got = %v, want %v", got, tt.want)
+				t.Errorf("IsTruncatedTLV() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRapdu_IsValidStatusWord(t *testing.T) {
+	tests := []struct {
+		name  string
+		rapdu Rapdu
+		want  bool
+	}{
+		{name: "9000", rapdu: Rapdu{SW1: 0x90, SW2: 0x00}, want: true},
+		{name: "61xx", rapdu: Rapdu{SW1: 0x61, SW2: 0x10}, want: true},
+		{name: "6A82", rapdu: Rapdu{SW1: 0x6A, SW2: 0x82}, want: true},
+		{name: "6FFF", rapdu: Rapdu{SW1: 0x6F, SW2: 0xFF}, want: true},
+		{name: "9FXX proprietary", rapdu: Rapdu{SW1: 0x9F, SW2: 0x10}, want: true},
+		{name: "60XX invalid", rapdu: Rapdu{SW1: 0x60, SW2: 0x00}, want: false},
+		{name: "0000 invalid", rapdu: Rapdu{SW1: 0x00, SW2: 0x00}, want: false},
+		{name: "5FXX invalid", rapdu: Rapdu{SW1: 0x5F, SW2: 0x00}, want: false},
+		{name: "A0XX invalid", rapdu: Rapdu{SW1: 0xA0, SW2: 0x00}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rapdu.IsValidStatusWord(); got != tt.want {
+				t.Errorf("IsValidStatusWord() = %v, want %v", got, tt.want)
 			}
 		})
 	}
@@ -690,6 +3564,95 @@ func TestRapdu_IsSuccess(t *testing.T) {
 	}
 }
 
+func TestRapdu_Split(t *testing.T) {
+	tests := []struct {
+		name     string
+		rapdu    Rapdu
+		wantData []byte
+		wantSW   uint16
+	}{
+		{
+			name:     "data and SW",
+			rapdu:    Rapdu{Data: []byte{0x01, 0x02}, SW1: 0x90, SW2: 0x00},
+			wantData: []byte{0x01, 0x02},
+			wantSW:   0x9000,
+		},
+		{
+			name:     "nil data",
+			rapdu:    Rapdu{SW1: 0x6A, SW2: 0x82},
+			wantData: nil,
+			wantSW:   0x6A82,
+		},
+		{
+			name:     "empty data",
+			rapdu:    Rapdu{Data: []byte{}, SW1: 0x62, SW2: 0x83},
+			wantData: []byte{},
+			wantSW:   0x6283,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotData, gotSW := tt.rapdu.Split()
+			if !reflect.DeepEqual(gotData, tt.wantData) {
+				t.Errorf("Split() gotData = %#v, want %#v", gotData, tt.wantData)
+			}
+
+			if gotSW != tt.wantSW {
+				t.Errorf("Split() gotSW = %04X, want %04X", gotSW, tt.wantSW)
+			}
+		})
+	}
+}
+
+func TestRapdu_IsSuccessWith(t *testing.T) {
+	tests := []struct {
+		name  string
+		rapdu Rapdu
+		extra []uint16
+		want  bool
+	}{
+		{
+			name:  "9000 without extra",
+			rapdu: Rapdu{SW1: 0x90, SW2: 0x00},
+			extra: nil,
+			want:  true,
+		},
+		{
+			name:  "61xx with extra",
+			rapdu: Rapdu{SW1: 0x61, SW2: 0x05},
+			extra: []uint16{0x6310},
+			want:  true,
+		},
+		{
+			name:  "custom success SW",
+			rapdu: Rapdu{SW1: 0x63, SW2: 0x10},
+			extra: []uint16{0x6283, 0x6310},
+			want:  true,
+		},
+		{
+			name:  "custom success SW not given",
+			rapdu: Rapdu{SW1: 0x63, SW2: 0x10},
+			extra: nil,
+			want:  false,
+		},
+		{
+			name:  "not in extra",
+			rapdu: Rapdu{SW1: 0x6A, SW2: 0x82},
+			extra: []uint16{0x6310},
+			want:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rapdu.IsSuccessWith(tt.extra...); got != tt.want {
+				t.Errorf("IsSuccessWith() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestRapdu_IsWarning(t *testing.T) {
 	type fields struct {
 		Data []byte
@@ -738,6 +3701,53 @@ func TestRapdu_IsWarning(t *testing.T) {
 	}
 }
 
+func TestRapdu_StateChanged(t *testing.T) {
+	tests := []struct {
+		name        string
+		rapdu       *Rapdu
+		wantChanged bool
+		wantKnown   bool
+	}{
+		{
+			name:        "warning 0x62, unchanged",
+			rapdu:       &Rapdu{SW1: 0x62, SW2: 0x83},
+			wantChanged: false,
+			wantKnown:   true,
+		},
+		{
+			name:        "warning 0x63, changed",
+			rapdu:       &Rapdu{SW1: 0x63, SW2: 0xC1},
+			wantChanged: true,
+			wantKnown:   true,
+		},
+		{
+			name:        "success, not warning",
+			rapdu:       &Rapdu{SW1: 0x90, SW2: 0x00},
+			wantChanged: false,
+			wantKnown:   false,
+		},
+		{
+			name:        "error, not warning",
+			rapdu:       &Rapdu{SW1: 0x65, SW2: 0x81},
+			wantChanged: false,
+			wantKnown:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotChanged, gotKnown := tt.rapdu.StateChanged()
+			if gotChanged != tt.wantChanged {
+				t.Errorf("StateChanged() gotChanged = %v, want %v", gotChanged, tt.wantChanged)
+			}
+
+			if gotKnown != tt.wantKnown {
+				t.Errorf("StateChanged() gotKnown = %v, want %v", gotKnown, tt.wantKnown)
+			}
+		})
+	}
+}
+
 func TestRapdu_IsError(t *testing.T) {
 	type fields struct {
 		Data []byte
@@ -963,3 +3973,307 @@ func BenchmarkRapdu_BytesOTrailerOnly(b *testing.B) {
 func BenchmarkRapdu_BytesTrailerAndData(b *testing.B) {
 	benchmarkRapduBytes(Rapdu{Data: []byte{0x01, 0x02, 0x03, 0x04, 0x05}, SW1: 0x90, SW2: 0x00}, b)
 }
+
+func TestRapdu_HasMoreData(t *testing.T) {
+	tests := []struct {
+		name  string
+		rapdu Rapdu
+		want  bool
+	}{
+		{
+			name:  "more data 0x61",
+			rapdu: Rapdu{SW1: 0x61, SW2: 0x10},
+			want:  true,
+		},
+		{
+			name:  "success 0x9000",
+			rapdu: Rapdu{SW1: 0x90, SW2: 0x00},
+			want:  false,
+		},
+		{
+			name:  "wrong Le 0x6C",
+			rapdu: Rapdu{SW1: 0x6C, SW2: 0x10},
+			want:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rapdu.HasMoreData(); got != tt.want {
+				t.Errorf("HasMoreData() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRapdu_IsTerminal(t *testing.T) {
+	tests := []struct {
+		name  string
+		rapdu Rapdu
+		want  bool
+	}{
+		{
+			name:  "more data 0x6110",
+			rapdu: Rapdu{SW1: 0x61, SW2: 0x10},
+			want:  false,
+		},
+		{
+			name:  "wrong Le 0x6C10",
+			rapdu: Rapdu{SW1: 0x6C, SW2: 0x10},
+			want:  false,
+		},
+		{
+			name:  "success 0x9000",
+			rapdu: Rapdu{Data: []byte{0x01}, SW1: 0x90, SW2: 0x00},
+			want:  true,
+		},
+		{
+			name:  "warning 0x6283",
+			rapdu: Rapdu{SW1: 0x62, SW2: 0x83},
+			want:  true,
+		},
+		{
+			name:  "file not found 0x6A82",
+			rapdu: Rapdu{SW1: 0x6A, SW2: 0x82},
+			want:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rapdu.IsTerminal(); got != tt.want {
+				t.Errorf("IsTerminal() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRapdu_CorrectLe(t *testing.T) {
+	tests := []struct {
+		name  string
+		rapdu Rapdu
+		want  int
+		want1 bool
+	}{
+		{
+			name:  "wrong Le 0x6C10",
+			rapdu: Rapdu{SW1: 0x6C, SW2: 0x10},
+			want:  16,
+			want1: true,
+		},
+		{
+			name:  "wrong Le 0x6C00",
+			rapdu: Rapdu{SW1: 0x6C, SW2: 0x00},
+			want:  256,
+			want1: true,
+		},
+		{
+			name:  "success 0x9000",
+			rapdu: Rapdu{SW1: 0x90, SW2: 0x00},
+			want:  0,
+			want1: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, got1 := tt.rapdu.CorrectLe()
+			if got != tt.want {
+				t.Errorf("CorrectLe() got = %v, want %v", got, tt.want)
+			}
+			if got1 != tt.want1 {
+				t.Errorf("CorrectLe() got1 = %v, want %v", got1, tt.want1)
+			}
+		})
+	}
+}
+
+func TestConcatData(t *testing.T) {
+	tests := []struct {
+		name   string
+		rapdus []*Rapdu
+		want   []byte
+	}{
+		{
+			name: "multiple responses",
+			rapdus: []*Rapdu{
+				{Data: []byte{0x01, 0x02}, SW1: 0x61, SW2: 0x02},
+				{Data: []byte{0x03, 0x04}, SW1: 0x61, SW2: 0x01},
+				{Data: []byte{0x05}, SW1: 0x90, SW2: 0x00},
+			},
+			want: []byte{0x01, 0x02, 0x03, 0x04, 0x05},
+		},
+		{
+			name: "responses with empty data",
+			rapdus: []*Rapdu{
+				{Data: []byte{0x01}, SW1: 0x90, SW2: 0x00},
+				{SW1: 0x62, SW2: 0x82},
+				nil,
+				{Data: []byte{}, SW1: 0x90, SW2: 0x00},
+				{Data: []byte{0x02}, SW1: 0x6A, SW2: 0x82},
+			},
+			want: []byte{0x01, 0x02},
+		},
+		{
+			name:   "only empty data",
+			rapdus: []*Rapdu{{SW1: 0x90, SW2: 0x00}},
+			want:   []byte{},
+		},
+		{
+			name:   "no responses",
+			rapdus: nil,
+			want:   []byte{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ConcatData(tt.rapdus...); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ConcatData() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsCompleteExchange(t *testing.T) {
+	type args struct {
+		c *Capdu
+		r *Rapdu
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{
+			name: "9000 with data within Ne",
+			args: args{
+				c: &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 4},
+				r: &Rapdu{Data: []byte{0x01, 0x02, 0x03, 0x04}, SW1: 0x90, SW2: 0x00},
+			},
+			want: true,
+		},
+		{
+			name: "9000 without data for Case 3",
+			args: args{
+				c: &Capdu{Cla: 0x00, Ins: 0xD6, P1: 0x00, P2: 0x00, Data: []byte{0x01}},
+				r: &Rapdu{SW1: 0x90, SW2: 0x00},
+			},
+			want: true,
+		},
+		{
+			name: "error status word is terminal",
+			args: args{
+				c: &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0x01}, Ne: 256},
+				r: &Rapdu{SW1: 0x6A, SW2: 0x82},
+			},
+			want: true,
+		},
+		{
+			name: "61xx requires GET RESPONSE",
+			args: args{
+				c: &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0x01}, Ne: 256},
+				r: &Rapdu{Data: []byte{0x01, 0x02}, SW1: 0x61, SW2: 0x10},
+			},
+			want: false,
+		},
+		{
+			name: "6Cxx requires retry",
+			args: args{
+				c: &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 256},
+				r: &Rapdu{SW1: 0x6C, SW2: 0x10},
+			},
+			want: false,
+		},
+		{
+			name: "data exceeds Ne",
+			args: args{
+				c: &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 2},
+				r: &Rapdu{Data: []byte{0x01, 0x02, 0x03}, SW1: 0x90, SW2: 0x00},
+			},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsCompleteExchange(tt.args.c, tt.args.r); got != tt.want {
+				t.Errorf("IsCompleteExchange() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConsistentExchange(t *testing.T) {
+	tests := []struct {
+		name       string
+		c          *Capdu
+		r          *Rapdu
+		want       bool
+		wantReason string
+	}{
+		{
+			name:       "Case 1 without response data",
+			c:          &Capdu{Cla: 0x00, Ins: 0x44, P1: 0x00, P2: 0x00},
+			r:          &Rapdu{SW1: 0x90, SW2: 0x00},
+			want:       true,
+			wantReason: "",
+		},
+		{
+			name:       "Case 2 with response data within Ne",
+			c:          &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 4},
+			r:          &Rapdu{Data: []byte{0x01, 0x02, 0x03, 0x04}, SW1: 0x90, SW2: 0x00},
+			want:       true,
+			wantReason: "",
+		},
+		{
+			name:       "Case 4 with error and without response data",
+			c:          &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0x01}, Ne: 256},
+			r:          &Rapdu{SW1: 0x6A, SW2: 0x82},
+			want:       true,
+			wantReason: "",
+		},
+		{
+			name:       "Case 3 with response data",
+			c:          &Capdu{Cla: 0x00, Ins: 0xD6, P1: 0x00, P2: 0x00, Data: []byte{0x01}},
+			r:          &Rapdu{Data: []byte{0x01, 0x02}, SW1: 0x90, SW2: 0x00},
+			want:       false,
+			wantReason: "response contains 2 byte of data although the command expects none",
+		},
+		{
+			name:       "Case 2 with response data exceeding Ne",
+			c:          &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 2},
+			r:          &Rapdu{Data: []byte{0x01, 0x02, 0x03}, SW1: 0x90, SW2: 0x00},
+			want:       false,
+			wantReason: "response contains 3 byte of data although the command expects at most 2 byte",
+		},
+		{
+			name:       "negative Ne",
+			c:          &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: -1},
+			r:          &Rapdu{SW1: 0x90, SW2: 0x00},
+			want:       false,
+			wantReason: "command has invalid Ne -1 - must be in the range 0 to 65536",
+		},
+		{
+			name:       "Ne exceeds maximum",
+			c:          &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 65537},
+			r:          &Rapdu{Data: []byte{0x01}, SW1: 0x90, SW2: 0x00},
+			want:       false,
+			wantReason: "command has invalid Ne 65537 - must be in the range 0 to 65536",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotReason := ConsistentExchange(tt.c, tt.r)
+			if got != tt.want {
+				t.Errorf("ConsistentExchange() got = %v, want %v", got, tt.want)
+			}
+
+			if gotReason != tt.wantReason {
+				t.Errorf("ConsistentExchange() gotReason = %v, want %v", gotReason, tt.wantReason)
+			}
+		})
+	}
+}
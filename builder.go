@@ -0,0 +1,67 @@
+package apdu
+
+import "github.com/pkg/errors"
+
+// CapduBuilder allows to construct a Capdu with chainable methods, e.g.
+// NewBuilder(0xA4).P1P2(0x04, 0x00).Data(aid).Ne(256).Build().
+// The Capdu is validated when Build is called.
+type CapduBuilder struct {
+	c Capdu
+}
+
+// NewBuilder returns a CapduBuilder for a Capdu with the given instruction byte and class byte '00'.
+func NewBuilder(ins byte) *CapduBuilder {
+	return &CapduBuilder{c: Capdu{Ins: ins}}
+}
+
+// Cla sets the class byte.
+func (b *CapduBuilder) Cla(cla byte) *CapduBuilder {
+	b.c.Cla = cla
+
+	return b
+}
+
+// P1P2 sets the parameter bytes P1 and P2.
+func (b *CapduBuilder) P1P2(p1, p2 byte) *CapduBuilder {
+	b.c.P1 = p1
+	b.c.P2 = p2
+
+	return b
+}
+
+// Data sets a copy of data as command data.
+func (b *CapduBuilder) Data(data []byte) *CapduBuilder {
+	if data == nil {
+		b.c.Data = nil
+
+		return b
+	}
+
+	b.c.Data = make([]byte, len(data))
+	copy(b.c.Data, data)
+
+	return b
+}
+
+// Ne sets the maximum number of expected response data bytes.
+func (b *CapduBuilder) Ne(ne int) *CapduBuilder {
+	b.c.Ne = ne
+
+	return b
+}
+
+// Build returns the Capdu. An error is returned if the length of the command data exceeds 65535 byte or if Ne is
+// negative or exceeds 65536.
+func (b *CapduBuilder) Build() (*Capdu, error) {
+	if len(b.c.Data) > MaxLenCommandDataExtended {
+		return nil, errors.Errorf("%s: len of Capdu.Data %d exceeds maximum allowed length of %d",
+			packageTag, len(b.c.Data), MaxLenCommandDataExtended)
+	}
+
+	if b.c.Ne < 0 || b.c.Ne > MaxLenResponseDataExtended {
+		return nil, errors.Errorf("%s: ne %d must be in the range 0 to %d",
+			packageTag, b.c.Ne, MaxLenResponseDataExtended)
+	}
+
+	return b.c.clone(), nil
+}
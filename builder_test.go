@@ -0,0 +1,101 @@
+package apdu
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCapduBuilder_Build(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder *CapduBuilder
+		want    *Capdu
+		wantErr bool
+	}{
+		{
+			name:    "Case 1",
+			builder: NewBuilder(0x70).Cla(0x00).P1P2(0x80, 0x01),
+			want:    &Capdu{Cla: 0x00, Ins: 0x70, P1: 0x80, P2: 0x01},
+			wantErr: false,
+		},
+		{
+			name:    "Case 2",
+			builder: NewBuilder(0xB0).Ne(256),
+			want:    &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 256},
+			wantErr: false,
+		},
+		{
+			name:    "Case 3",
+			builder: NewBuilder(0xD6).Cla(0x80).P1P2(0x00, 0x10).Data([]byte{0x01, 0x02}),
+			want:    &Capdu{Cla: 0x80, Ins: 0xD6, P1: 0x00, P2: 0x10, Data: []byte{0x01, 0x02}},
+			wantErr: false,
+		},
+		{
+			name:    "Case 4",
+			builder: NewBuilder(0xA4).P1P2(0x04, 0x00).Data([]byte{0xA0, 0x00, 0x00, 0x01, 0x51}).Ne(256),
+			want:    &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0xA0, 0x00, 0x00, 0x01, 0x51}, Ne: 256},
+			wantErr: false,
+		},
+		{
+			name:    "Case 4 extended",
+			builder: NewBuilder(0xA4).P1P2(0x04, 0x00).Data([]byte{0x01}).Ne(65536),
+			want:    &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0x01}, Ne: 65536},
+			wantErr: false,
+		},
+		{
+			name:    "error: data too long",
+			builder: NewBuilder(0xD6).Data(make([]byte, 65536)),
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: Ne too large",
+			builder: NewBuilder(0xB0).Ne(65537),
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: negative Ne",
+			builder: NewBuilder(0xB0).Ne(-1),
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.builder.Build()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Build() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Build() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapduBuilder_Data_Copy(t *testing.T) {
+	data := []byte{0x01, 0x02}
+	b := NewBuilder(0xD6).Data(data)
+	data[0] = 0xFF
+
+	c, err := b.Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	c.Data[1] = 0xFF
+
+	again, err := b.Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(again.Data, []byte{0x01, 0x02}) {
+		t.Errorf("Build() got Data = %X, want %X", again.Data, []byte{0x01, 0x02})
+	}
+}
@@ -0,0 +1,130 @@
+package apdu
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// CardChannel is implemented by types that transmit Command APDUs to a card and return the Response APDU.
+type CardChannel interface {
+	Transmit(c *Capdu) (*Rapdu, error)
+}
+
+// MockChannel is a CardChannel that records all transmitted Capdus and returns pre-programmed Rapdus in sequence
+// without sending anything to a card. It allows to test code that generates commands.
+// A MockChannel is safe for concurrent use.
+type MockChannel struct {
+	mu          sync.Mutex
+	responses   []*Rapdu
+	transmitted []*Capdu
+}
+
+// NewMockChannel returns a MockChannel that returns the given responses in the given order.
+func NewMockChannel(responses ...*Rapdu) *MockChannel {
+	return &MockChannel{responses: responses}
+}
+
+// Transmit records a copy of c and returns a copy of the next pre-programmed Rapdu.
+// An error is returned if c is nil, if the MockChannel ran out of responses or if the next pre-programmed Rapdu is
+// nil, which is consumed nonetheless.
+func (m *MockChannel) Transmit(c *Capdu) (*Rapdu, error) {
+	if c == nil {
+		return nil, errors.Errorf("%s: Capdu must not be nil", packageTag)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.transmitted = append(m.transmitted, c.clone())
+
+	if len(m.responses) == 0 {
+		return nil, errors.Errorf("%s: no response left for Capdu %d", packageTag, len(m.transmitted))
+	}
+
+	next := m.responses[0]
+	m.responses = m.responses[1:]
+
+	if next == nil {
+		return nil, errors.Errorf("%s: pre-programmed response for Capdu %d is nil", packageTag, len(m.transmitted))
+	}
+
+	r := *next
+	r.Data = append([]byte(nil), next.Data...)
+
+	return &r, nil
+}
+
+// Transmitted returns copies of all Capdus that have been transmitted so far in the order they were transmitted.
+func (m *MockChannel) Transmitted() []*Capdu {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	transmitted := make([]*Capdu, 0, len(m.transmitted))
+	for _, c := range m.transmitted {
+		transmitted = append(transmitted, c.clone())
+	}
+
+	return transmitted
+}
+
+// serializingChannel is a CardChannel that serializes calls of Transmit of the wrapped CardChannel.
+type serializingChannel struct {
+	mu sync.Mutex
+	ch CardChannel
+}
+
+// SerializingChannel returns a CardChannel that wraps ch and serializes concurrent calls of Transmit with a mutex,
+// so that exchanges of different goroutines can't interleave.
+func SerializingChannel(ch CardChannel) CardChannel {
+	return &serializingChannel{ch: ch}
+}
+
+// Transmit calls Transmit of the wrapped CardChannel once all preceding calls have returned.
+func (s *serializingChannel) Transmit(c *Capdu) (*Rapdu, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.ch.Transmit(c)
+}
+
+// retryingChannel is a CardChannel that retries Transmit of the wrapped CardChannel on errors.
+type retryingChannel struct {
+	ch         CardChannel
+	maxRetries int
+	backoff    time.Duration
+}
+
+// RetryingChannel returns a CardChannel that wraps ch and retries Transmit up to maxRetries times with a fixed
+// backoff between the attempts if ch returns an error, e.g. because of a flaky reader. Responses that indicate an
+// error in their status word are returned as they are and are not retried, since the card processed the command.
+// A negative maxRetries is treated as zero.
+func RetryingChannel(ch CardChannel, maxRetries int, backoff time.Duration) CardChannel {
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+
+	return &retryingChannel{ch: ch, maxRetries: maxRetries, backoff: backoff}
+}
+
+// Transmit calls Transmit of the wrapped CardChannel until it returns no error or the maximum number of retries is
+// reached. In the latter case the error of the last attempt is returned.
+func (r *retryingChannel) Transmit(c *Capdu) (*Rapdu, error) {
+	var err error
+
+	for attempt := 0; attempt <= r.maxRetries; attempt++ {
+		if attempt > 0 && r.backoff > 0 {
+			time.Sleep(r.backoff)
+		}
+
+		var resp *Rapdu
+
+		resp, err = r.ch.Transmit(c)
+		if err == nil {
+			return resp, nil
+		}
+	}
+
+	return nil, errors.Wrapf(err, "%s: transmit failed after %d attempts", packageTag, r.maxRetries+1)
+}
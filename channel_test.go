@@ -0,0 +1,344 @@
+package apdu
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+var _ CardChannel = (*MockChannel)(nil)
+
+func TestMockChannel_Transmit(t *testing.T) {
+	tests := []struct {
+		name            string
+		responses       []*Rapdu
+		cmds            []*Capdu
+		want            []*Rapdu
+		wantTransmitted []*Capdu
+		wantErr         bool
+	}{
+		{
+			name:            "single exchange",
+			responses:       []*Rapdu{{Data: []byte{0x01}, SW1: 0x90, SW2: 0x00}},
+			cmds:            []*Capdu{{Cla: 0x00, Ins: 0xB0, Ne: 1}},
+			want:            []*Rapdu{{Data: []byte{0x01}, SW1: 0x90, SW2: 0x00}},
+			wantTransmitted: []*Capdu{{Cla: 0x00, Ins: 0xB0, Ne: 1}},
+			wantErr:         false,
+		},
+		{
+			name: "responses in sequence",
+			responses: []*Rapdu{
+				{SW1: 0x90, SW2: 0x00},
+				{SW1: 0x6A, SW2: 0x82},
+			},
+			cmds: []*Capdu{
+				{Cla: 0x00, Ins: 0xA4, P1: 0x04, Data: []byte{0xA0, 0x00}},
+				{Cla: 0x00, Ins: 0xA4, P1: 0x04, Data: []byte{0xA0, 0x01}},
+			},
+			want: []*Rapdu{
+				{SW1: 0x90, SW2: 0x00},
+				{SW1: 0x6A, SW2: 0x82},
+			},
+			wantTransmitted: []*Capdu{
+				{Cla: 0x00, Ins: 0xA4, P1: 0x04, Data: []byte{0xA0, 0x00}},
+				{Cla: 0x00, Ins: 0xA4, P1: 0x04, Data: []byte{0xA0, 0x01}},
+			},
+			wantErr: false,
+		},
+		{
+			name:            "error: out of responses",
+			responses:       []*Rapdu{{SW1: 0x90, SW2: 0x00}},
+			cmds:            []*Capdu{{Cla: 0x00, Ins: 0x70}, {Cla: 0x00, Ins: 0x70, P1: 0x80, P2: 0x01}},
+			want:            []*Rapdu{{SW1: 0x90, SW2: 0x00}},
+			wantTransmitted: []*Capdu{{Cla: 0x00, Ins: 0x70}, {Cla: 0x00, Ins: 0x70, P1: 0x80, P2: 0x01}},
+			wantErr:         true,
+		},
+		{
+			name:            "error: nil response",
+			responses:       []*Rapdu{{SW1: 0x90, SW2: 0x00}, nil},
+			cmds:            []*Capdu{{Cla: 0x00, Ins: 0x70}, {Cla: 0x00, Ins: 0x70, P1: 0x80, P2: 0x01}},
+			want:            []*Rapdu{{SW1: 0x90, SW2: 0x00}},
+			wantTransmitted: []*Capdu{{Cla: 0x00, Ins: 0x70}, {Cla: 0x00, Ins: 0x70, P1: 0x80, P2: 0x01}},
+			wantErr:         true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMockChannel(tt.responses...)
+
+			var (
+				got []*Rapdu
+				err error
+			)
+
+			for _, c := range tt.cmds {
+				var r *Rapdu
+
+				r, err = m.Transmit(c)
+				if err != nil {
+					break
+				}
+
+				got = append(got, r)
+			}
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Transmit() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Transmit() got = %v, want %v", got, tt.want)
+			}
+
+			if gotTransmitted := m.Transmitted(); !reflect.DeepEqual(gotTransmitted, tt.wantTransmitted) {
+				t.Errorf("Transmitted() got = %v, want %v", gotTransmitted, tt.wantTransmitted)
+			}
+		})
+	}
+}
+
+func TestMockChannel_Transmit_GetResponseLoop(t *testing.T) {
+	// readAll sends c and fetches the remaining response data with GET RESPONSE until the response is terminal
+	readAll := func(ch CardChannel, c *Capdu) ([]byte, *Rapdu, error) {
+		var data []byte
+
+		for {
+			r, err := ch.Transmit(c)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			data = append(data, r.Data...)
+
+			if r.IsTerminal() {
+				return data, r, nil
+			}
+
+			ne := int(r.SW2)
+			if ne == 0 {
+				ne = MaxLenResponseDataStandard
+			}
+
+			c = &Capdu{Cla: c.Cla, Ins: 0xC0, Ne: ne}
+		}
+	}
+
+	m := NewMockChannel(
+		&Rapdu{Data: []byte{0x01, 0x02}, SW1: 0x61, SW2: 0x02},
+		&Rapdu{Data: []byte{0x03, 0x04}, SW1: 0x90, SW2: 0x00},
+	)
+
+	data, r, err := readAll(m, &Capdu{Cla: 0x00, Ins: 0xCA, P1: 0x9F, P2: 0x7F, Ne: 256})
+	if err != nil {
+		t.Fatalf("Transmit() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(data, []byte{0x01, 0x02, 0x03, 0x04}) {
+		t.Errorf("Transmit() got data = %X, want %X", data, []byte{0x01, 0x02, 0x03, 0x04})
+	}
+
+	if !r.IsSuccess() {
+		t.Errorf("Transmit() got final SW %02X%02X, want 9000", r.SW1, r.SW2)
+	}
+
+	wantTransmitted := []*Capdu{
+		{Cla: 0x00, Ins: 0xCA, P1: 0x9F, P2: 0x7F, Ne: 256},
+		{Cla: 0x00, Ins: 0xC0, Ne: 2},
+	}
+
+	if got := m.Transmitted(); !reflect.DeepEqual(got, wantTransmitted) {
+		t.Errorf("Transmitted() got = %v, want %v", got, wantTransmitted)
+	}
+}
+
+func TestMockChannel_Transmit_Copies(t *testing.T) {
+	response := &Rapdu{Data: []byte{0x01}, SW1: 0x90, SW2: 0x00}
+	m := NewMockChannel(response)
+	c := &Capdu{Cla: 0x00, Ins: 0xD6, Data: []byte{0x0A}}
+
+	r, err := m.Transmit(c)
+	if err != nil {
+		t.Fatalf("Transmit() error = %v", err)
+	}
+
+	c.Data[0] = 0xFF
+	r.Data[0] = 0xFF
+
+	if got := m.Transmitted()[0].Data; !reflect.DeepEqual(got, []byte{0x0A}) {
+		t.Errorf("Transmitted() got Data = %X, want 0A", got)
+	}
+
+	if !reflect.DeepEqual(response.Data, []byte{0x01}) {
+		t.Errorf("Transmit() modified pre-programmed response to %X", response.Data)
+	}
+}
+
+// overlapDetectingChannel is a CardChannel that counts calls of Transmit that overlap with another call.
+type overlapDetectingChannel struct {
+	active   int32
+	overlaps int32
+	calls    int32
+}
+
+func (o *overlapDetectingChannel) Transmit(c *Capdu) (*Rapdu, error) {
+	if atomic.AddInt32(&o.active, 1) > 1 {
+		atomic.AddInt32(&o.overlaps, 1)
+	}
+
+	time.Sleep(time.Millisecond)
+	atomic.AddInt32(&o.calls, 1)
+	atomic.AddInt32(&o.active, -1)
+
+	return &Rapdu{SW1: 0x90, SW2: 0x00}, nil
+}
+
+func TestSerializingChannel_Transmit(t *testing.T) {
+	const goroutines = 10
+
+	inner := &overlapDetectingChannel{}
+	ch := SerializingChannel(inner)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			if _, err := ch.Transmit(&Capdu{Cla: 0x00, Ins: 0xB0, P2: byte(i), Ne: 1}); err != nil {
+				t.Errorf("Transmit() error = %v", err)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	if inner.calls != goroutines {
+		t.Errorf("Transmit() got %d calls, want %d", inner.calls, goroutines)
+	}
+
+	if inner.overlaps != 0 {
+		t.Errorf("Transmit() got %d overlapping calls, want 0", inner.overlaps)
+	}
+}
+
+// flakyChannel is a CardChannel that fails a given number of times before it returns the response.
+type flakyChannel struct {
+	failures int
+	calls    int
+	response *Rapdu
+}
+
+var errFlaky = errors.New("reader unavailable")
+
+func (f *flakyChannel) Transmit(c *Capdu) (*Rapdu, error) {
+	f.calls++
+
+	if f.calls <= f.failures {
+		return nil, errFlaky
+	}
+
+	return f.response, nil
+}
+
+func TestRetryingChannel_Transmit(t *testing.T) {
+	tests := []struct {
+		name       string
+		failures   int
+		maxRetries int
+		response   *Rapdu
+		want       *Rapdu
+		wantCalls  int
+		wantErr    bool
+	}{
+		{
+			name:       "success without retry",
+			failures:   0,
+			maxRetries: 3,
+			response:   &Rapdu{SW1: 0x90, SW2: 0x00},
+			want:       &Rapdu{SW1: 0x90, SW2: 0x00},
+			wantCalls:  1,
+			wantErr:    false,
+		},
+		{
+			name:       "success after retries",
+			failures:   2,
+			maxRetries: 3,
+			response:   &Rapdu{Data: []byte{0x01}, SW1: 0x90, SW2: 0x00},
+			want:       &Rapdu{Data: []byte{0x01}, SW1: 0x90, SW2: 0x00},
+			wantCalls:  3,
+			wantErr:    false,
+		},
+		{
+			name:       "success with last retry",
+			failures:   3,
+			maxRetries: 3,
+			response:   &Rapdu{SW1: 0x90, SW2: 0x00},
+			want:       &Rapdu{SW1: 0x90, SW2: 0x00},
+			wantCalls:  4,
+			wantErr:    false,
+		},
+		{
+			name:       "status word error is not retried",
+			failures:   0,
+			maxRetries: 3,
+			response:   &Rapdu{SW1: 0x6A, SW2: 0x82},
+			want:       &Rapdu{SW1: 0x6A, SW2: 0x82},
+			wantCalls:  1,
+			wantErr:    false,
+		},
+		{
+			name:       "error: retries exhausted",
+			failures:   4,
+			maxRetries: 3,
+			response:   &Rapdu{SW1: 0x90, SW2: 0x00},
+			want:       nil,
+			wantCalls:  4,
+			wantErr:    true,
+		},
+		{
+			name:       "error: negative retries",
+			failures:   1,
+			maxRetries: -1,
+			response:   &Rapdu{SW1: 0x90, SW2: 0x00},
+			want:       nil,
+			wantCalls:  1,
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &flakyChannel{failures: tt.failures, response: tt.response}
+			ch := RetryingChannel(inner, tt.maxRetries, time.Microsecond)
+
+			got, err := ch.Transmit(&Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, Data: []byte{0xA0, 0x00}})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Transmit() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if tt.wantErr && pkgerrors.Cause(err) != errFlaky {
+				t.Errorf("Transmit() error = %v, want wrapped %v", err, errFlaky)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Transmit() got = %v, want %v", got, tt.want)
+			}
+
+			if inner.calls != tt.wantCalls {
+				t.Errorf("Transmit() got %d calls, want %d", inner.calls, tt.wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package apdu
+
+import (
+	"github.com/pkg/errors"
+)
+
+// claProprietary is the bit of the class byte that indicates a proprietary class.
+const claProprietary byte = 0x80
+
+// claFurther is the bit of the class byte that indicates the further class encoding (logical channels 4 to 19).
+const claFurther byte = 0x40
+
+// claProprietarySM is the bit of a proprietary first class byte that indicates secure messaging as defined by
+// GlobalPlatform.
+const claProprietarySM byte = 0x04
+
+// claProprietaryFurtherSM is the bit of a proprietary further class byte that indicates secure messaging as defined by
+// GlobalPlatform.
+const claProprietaryFurtherSM byte = 0x20
+
+// isProprietaryClass returns true if cla indicates a proprietary class, else false. The value 0xFF is invalid.
+func isProprietaryClass(cla byte) bool {
+	return cla&claProprietary == claProprietary && cla != 0xFF
+}
+
+// WithSecureMessaging returns cla with the secure messaging bit set for proprietary classes according to the
+// GlobalPlatform convention, which uses bit 3 of the class byte for logical channels 0 to 3 (e.g. 0x80 -> 0x84) and
+// bit 6 of the class byte for logical channels 4 to 19 (e.g. 0xC1 -> 0xE1). The logical channel bits are retained.
+// The secure messaging indication of the interindustry classes is defined by ISO 7816-4 and differs from this
+// convention, therefore cla is returned unchanged for interindustry classes and the invalid class 0xFF.
+func WithSecureMessaging(cla byte) byte {
+	if !isProprietaryClass(cla) {
+		return cla
+	}
+
+	if cla&claFurther == claFurther {
+		return cla | claProprietaryFurtherSM
+	}
+
+	return cla | claProprietarySM
+}
+
+// Channel returns the number of the logical channel that is encoded in the class byte of the Capdu.
+// Classes '0X', '8X', '9X' and 'AX' encode the logical channels 0 to 3 in bits 2-1, classes '4X' to '7X' and
+// 'CX' to 'FX' encode the logical channels 4 to 19 in bits 4-1 (ISO 7816-4 and GlobalPlatform).
+// An error is returned for the RFU classes '2X' and '3X' and for the invalid class 'FF'.
+func (c *Capdu) Channel() (int, error) {
+	if !isValidClass(c.Cla) {
+		return 0, errors.Errorf("%s: class byte 0x%02X does not encode a logical channel", packageTag, c.Cla)
+	}
+
+	if c.Cla&claFurther == claFurther {
+		return 4 + int(c.Cla&0x0F), nil
+	}
+
+	return int(c.Cla & 0x03), nil
+}
+
+// ChannelAllowed returns true if the class byte of the Capdu encodes a logical channel that does not exceed max,
+// else false. False is returned as well if the class byte does not encode a logical channel (see Channel).
+func (c *Capdu) ChannelAllowed(max int) bool {
+	channel, err := c.Channel()
+	if err != nil {
+		return false
+	}
+
+	return channel <= max
+}
+
+// isValidClass returns true if cla is neither one of the RFU classes '2X' and '3X' nor the invalid class 'FF',
+// else false.
+func isValidClass(cla byte) bool {
+	return cla&0xE0 != 0x20 && cla != 0xFF
+}
+
+// classWithoutChannel returns cla in the first class encoding without logical channel, i.e. the channel bits are
+// cleared and a further class is converted to the corresponding first class with the same chaining and secure
+// messaging indication. RFU and invalid classes are returned unchanged.
+func classWithoutChannel(cla byte) byte {
+	if !isValidClass(cla) {
+		return cla
+	}
+
+	if cla&claFurther != claFurther {
+		return cla &^ 0x03
+	}
+
+	// chaining and proprietary bit are at the same position in first and further classes
+	first := cla & (claProprietary | 0x10)
+
+	if cla&0x20 == 0x20 {
+		if isProprietaryClass(cla) {
+			first |= claProprietarySM
+		} else {
+			// SM without authenticated command header (ISO 7816-4 first class bits 4-3 = 10)
+			first |= 0x08
+		}
+	}
+
+	return first
+}
+
+// claChaining is the bit of an interindustry class byte that indicates command chaining.
+const claChaining byte = 0x10
+
+// IsChaining returns true if the class byte of the Capdu indicates that the command is not the last command of a
+// chain (ISO 7816-4 command chaining), else false. Command chaining is only defined for interindustry classes,
+// therefore IsChaining returns false for proprietary, RFU and invalid classes.
+func (c *Capdu) IsChaining() bool {
+	return isValidClass(c.Cla) && !isProprietaryClass(c.Cla) && c.Cla&claChaining == claChaining
+}
+
+// IsChainedWith returns true if second is the successor of first in a command chain, i.e. first indicates command
+// chaining and both commands share the same class byte (ignoring the chaining bit), instruction, P1 and P2,
+// else false.
+func IsChainedWith(first, second *Capdu) bool {
+	if first == nil || second == nil || !first.IsChaining() {
+		return false
+	}
+
+	return first.Cla&^claChaining == second.Cla&^claChaining &&
+		first.Ins == second.Ins &&
+		first.P1 == second.P1 &&
+		first.P2 == second.P2
+}
+
+// ChainCount returns the number of commands that are required to transmit dataLen byte of command data in segments of
+// up to maxDataLen byte, e.g. with command chaining or as LOAD blocks. A single command is required for dataLen 0.
+// An error is returned if dataLen is negative or maxDataLen is not positive.
+func ChainCount(dataLen, maxDataLen int) (int, error) {
+	if dataLen < 0 {
+		return 0, errors.Errorf("%s: data length must not be negative, got %d", packageTag, dataLen)
+	}
+
+	if maxDataLen < 1 {
+		return 0, errors.Errorf("%s: maximum data length must be positive, got %d", packageTag, maxDataLen)
+	}
+
+	if dataLen == 0 {
+		return 1, nil
+	}
+
+	return (dataLen + maxDataLen - 1) / maxDataLen, nil
+}
+
+// GPSecLevel is the secure messaging level of a command with a proprietary class as indicated by the class byte
+// according to GlobalPlatform.
+type GPSecLevel int
+
+const (
+	// GPSecLevelUnknown is the level of commands with an interindustry, RFU or invalid class, for which the
+	// GlobalPlatform convention does not apply.
+	GPSecLevelUnknown GPSecLevel = iota
+	// GPSecLevelClear is the level of commands that are sent without secure messaging.
+	GPSecLevelClear
+	// GPSecLevelMAC is the level of commands that are sent with secure messaging, i.e. at least with a C-MAC.
+	GPSecLevelMAC
+)
+
+// String returns the name of the GPSecLevel.
+func (l GPSecLevel) String() string {
+	switch l {
+	case GPSecLevelClear:
+		return "CLEAR"
+	case GPSecLevelMAC:
+		return "MAC"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// GPSecurityLevel returns the secure messaging level that is indicated by the proprietary class byte of the Capdu
+// according to GlobalPlatform, i.e. bit 3 for logical channels 0 to 3 (e.g. 0x84) and bit 6 for logical channels 4 to
+// 19 (e.g. 0xE1). The class byte only indicates whether secure messaging applies, not whether the command data is
+// encrypted in addition to the C-MAC, which depends on the security level of the secure channel session.
+// GPSecLevelUnknown is returned for interindustry classes, whose secure messaging indication is defined by
+// ISO 7816-4, and for RFU or invalid classes.
+func (c *Capdu) GPSecurityLevel() GPSecLevel {
+	if !isProprietaryClass(c.Cla) {
+		return GPSecLevelUnknown
+	}
+
+	if WithSecureMessaging(c.Cla) == c.Cla {
+		return GPSecLevelMAC
+	}
+
+	return GPSecLevelClear
+}
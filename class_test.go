@@ -0,0 +1,292 @@
+package apdu
+
+import (
+	"testing"
+)
+
+func TestWithSecureMessaging(t *testing.T) {
+	tests := []struct {
+		name string
+		cla  byte
+		want byte
+	}{
+		{name: "proprietary class", cla: 0x80, want: 0x84},
+		{name: "proprietary class with SM", cla: 0x84, want: 0x84},
+		{name: "proprietary class on logical channel", cla: 0x81, want: 0x85},
+		{name: "proprietary class further logical channel", cla: 0xC1, want: 0xE1},
+		{name: "proprietary class further logical channel with SM", cla: 0xE1, want: 0xE1},
+		{name: "interindustry class unchanged", cla: 0x00, want: 0x00},
+		{name: "interindustry class with chaining unchanged", cla: 0x10, want: 0x10},
+		{name: "invalid class unchanged", cla: 0xFF, want: 0xFF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WithSecureMessaging(tt.cla); got != tt.want {
+				t.Errorf("WithSecureMessaging() = %02X, want %02X", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapdu_Channel(t *testing.T) {
+	tests := []struct {
+		name    string
+		cla     byte
+		want    int
+		wantErr bool
+	}{
+		{name: "interindustry channel 0", cla: 0x00, want: 0, wantErr: false},
+		{name: "interindustry channel 3 with SM", cla: 0x0F, want: 3, wantErr: false},
+		{name: "interindustry channel 2 with chaining", cla: 0x12, want: 2, wantErr: false},
+		{name: "interindustry further channel 4", cla: 0x40, want: 4, wantErr: false},
+		{name: "interindustry further channel 19 with SM", cla: 0x6F, want: 19, wantErr: false},
+		{name: "proprietary channel 1", cla: 0x81, want: 1, wantErr: false},
+		{name: "proprietary channel 2 with SM", cla: 0x86, want: 2, wantErr: false},
+		{name: "proprietary further channel 5", cla: 0xC1, want: 5, wantErr: false},
+		{name: "proprietary further channel 19 with SM", cla: 0xEF, want: 19, wantErr: false},
+		{name: "error: RFU class", cla: 0x20, want: 0, wantErr: true},
+		{name: "error: invalid class", cla: 0xFF, want: 0, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Capdu{Cla: tt.cla, Ins: 0xA4}
+			got, err := c.Channel()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Channel() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Channel() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_classWithoutChannel(t *testing.T) {
+	tests := []struct {
+		name string
+		cla  byte
+		want byte
+	}{
+		{name: "interindustry channel 2", cla: 0x02, want: 0x00},
+		{name: "interindustry chaining and SM channel 3", cla: 0x1F, want: 0x1C},
+		{name: "interindustry further channel 5", cla: 0x41, want: 0x00},
+		{name: "interindustry further with SM and chaining", cla: 0x7F, want: 0x18},
+		{name: "proprietary with SM channel 1", cla: 0x85, want: 0x84},
+		{name: "proprietary further with SM", cla: 0xE3, want: 0x84},
+		{name: "proprietary further without SM", cla: 0xC3, want: 0x80},
+		{name: "RFU class unchanged", cla: 0x21, want: 0x21},
+		{name: "invalid class unchanged", cla: 0xFF, want: 0xFF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classWithoutChannel(tt.cla); got != tt.want {
+				t.Errorf("classWithoutChannel() = %02X, want %02X", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapdu_IsChaining(t *testing.T) {
+	tests := []struct {
+		name string
+		cla  byte
+		want bool
+	}{
+		{name: "interindustry chaining", cla: 0x10, want: true},
+		{name: "interindustry chaining with SM on channel 3", cla: 0x1F, want: true},
+		{name: "interindustry further chaining", cla: 0x51, want: true},
+		{name: "interindustry last command", cla: 0x00, want: false},
+		{name: "proprietary", cla: 0x90, want: false},
+		{name: "RFU", cla: 0x30, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Capdu{Cla: tt.cla, Ins: 0xDB}
+			if got := c.IsChaining(); got != tt.want {
+				t.Errorf("IsChaining() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsChainedWith(t *testing.T) {
+	tests := []struct {
+		name   string
+		first  *Capdu
+		second *Capdu
+		want   bool
+	}{
+		{
+			name:   "chained pair",
+			first:  &Capdu{Cla: 0x10, Ins: 0xDB, P1: 0x3F, P2: 0xFF, Data: []byte{0x01}},
+			second: &Capdu{Cla: 0x00, Ins: 0xDB, P1: 0x3F, P2: 0xFF, Data: []byte{0x02}},
+			want:   true,
+		},
+		{
+			name:   "chained pair in the middle of a chain",
+			first:  &Capdu{Cla: 0x12, Ins: 0xDB, P1: 0x3F, P2: 0xFF, Data: []byte{0x01}},
+			second: &Capdu{Cla: 0x12, Ins: 0xDB, P1: 0x3F, P2: 0xFF, Data: []byte{0x02}},
+			want:   true,
+		},
+		{
+			name:   "first not chaining",
+			first:  &Capdu{Cla: 0x00, Ins: 0xDB, P1: 0x3F, P2: 0xFF, Data: []byte{0x01}},
+			second: &Capdu{Cla: 0x00, Ins: 0xDB, P1: 0x3F, P2: 0xFF, Data: []byte{0x02}},
+			want:   false,
+		},
+		{
+			name:   "unrelated pair",
+			first:  &Capdu{Cla: 0x10, Ins: 0xDB, P1: 0x3F, P2: 0xFF, Data: []byte{0x01}},
+			second: &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0x02}},
+			want:   false,
+		},
+		{
+			name:   "different channel",
+			first:  &Capdu{Cla: 0x10, Ins: 0xDB, P1: 0x3F, P2: 0xFF, Data: []byte{0x01}},
+			second: &Capdu{Cla: 0x01, Ins: 0xDB, P1: 0x3F, P2: 0xFF, Data: []byte{0x02}},
+			want:   false,
+		},
+		{
+			name:   "nil",
+			first:  &Capdu{Cla: 0x10, Ins: 0xDB, P1: 0x3F, P2: 0xFF, Data: []byte{0x01}},
+			second: nil,
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsChainedWith(tt.first, tt.second); got != tt.want {
+				t.Errorf("IsChainedWith() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChainCount(t *testing.T) {
+	tests := []struct {
+		name       string
+		dataLen    int
+		maxDataLen int
+		want       int
+		wantErr    bool
+	}{
+		{name: "no data", dataLen: 0, maxDataLen: 255, want: 1, wantErr: false},
+		{name: "single command", dataLen: 100, maxDataLen: 255, want: 1, wantErr: false},
+		{name: "exact multiple", dataLen: 510, maxDataLen: 255, want: 2, wantErr: false},
+		{name: "remainder", dataLen: 511, maxDataLen: 255, want: 3, wantErr: false},
+		{name: "one byte segments", dataLen: 3, maxDataLen: 1, want: 3, wantErr: false},
+		{name: "error: negative data length", dataLen: -1, maxDataLen: 255, want: 0, wantErr: true},
+		{name: "error: zero maximum data length", dataLen: 10, maxDataLen: 0, want: 0, wantErr: true},
+		{name: "error: negative maximum data length", dataLen: 10, maxDataLen: -5, want: 0, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ChainCount(tt.dataLen, tt.maxDataLen)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ChainCount() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if got != tt.want {
+				t.Errorf("ChainCount() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChainCount_MatchesLoadBlocks(t *testing.T) {
+	for _, dataLen := range []int{1, 239, 240, 241, 480, 1000} {
+		cmds, err := LoadBlocks(0x80, make([]byte, dataLen), 240)
+		if err != nil {
+			t.Fatalf("LoadBlocks() error = %v", err)
+		}
+
+		got, err := ChainCount(dataLen, 240)
+		if err != nil {
+			t.Fatalf("ChainCount() error = %v", err)
+		}
+
+		if got != len(cmds) {
+			t.Errorf("ChainCount(%d, 240) = %d, LoadBlocks() returned %d commands", dataLen, got, len(cmds))
+		}
+	}
+}
+
+func TestCapdu_GPSecurityLevel(t *testing.T) {
+	tests := []struct {
+		name string
+		cla  byte
+		want GPSecLevel
+	}{
+		{name: "proprietary clear", cla: 0x80, want: GPSecLevelClear},
+		{name: "proprietary SM", cla: 0x84, want: GPSecLevelMAC},
+		{name: "proprietary SM on channel 3", cla: 0x87, want: GPSecLevelMAC},
+		{name: "proprietary further clear", cla: 0xC1, want: GPSecLevelClear},
+		{name: "proprietary further SM", cla: 0xE1, want: GPSecLevelMAC},
+		{name: "interindustry", cla: 0x04, want: GPSecLevelUnknown},
+		{name: "invalid", cla: 0xFF, want: GPSecLevelUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Capdu{Cla: tt.cla, Ins: 0xE6}
+			if got := c.GPSecurityLevel(); got != tt.want {
+				t.Errorf("GPSecurityLevel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGPSecLevel_String(t *testing.T) {
+	tests := []struct {
+		name  string
+		level GPSecLevel
+		want  string
+	}{
+		{name: "clear", level: GPSecLevelClear, want: "CLEAR"},
+		{name: "MAC", level: GPSecLevelMAC, want: "MAC"},
+		{name: "unknown", level: GPSecLevelUnknown, want: "UNKNOWN"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.level.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapdu_ChannelAllowed(t *testing.T) {
+	tests := []struct {
+		name string
+		cla  byte
+		max  int
+		want bool
+	}{
+		{name: "basic channel", cla: 0x00, max: 0, want: true},
+		{name: "channel 1 with max 0", cla: 0x01, max: 0, want: false},
+		{name: "channel 3 with max 3", cla: 0x83, max: 3, want: true},
+		{name: "further channel 4 with max 3", cla: 0x40, max: 3, want: false},
+		{name: "further channel 19 with max 19", cla: 0x4F, max: 19, want: true},
+		{name: "RFU class", cla: 0x20, max: 19, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Capdu{Cla: tt.cla, Ins: 0xA4}
+			if got := c.ChannelAllowed(tt.max); got != tt.want {
+				t.Errorf("ChannelAllowed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
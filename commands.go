@@ -0,0 +1,252 @@
+package apdu
+
+import (
+	"github.com/pkg/errors"
+)
+
+const (
+	// InsExternalAuthenticate is the instruction byte of EXTERNAL AUTHENTICATE.
+	InsExternalAuthenticate byte = 0x82
+	// InsGetChallenge is the instruction byte of GET CHALLENGE.
+	InsGetChallenge byte = 0x84
+	// InsInternalAuthenticate is the instruction byte of INTERNAL AUTHENTICATE.
+	InsInternalAuthenticate byte = 0x88
+	// InsSelect is the instruction byte of SELECT.
+	InsSelect byte = 0xA4
+	// InsGetResponse is the instruction byte of GET RESPONSE.
+	InsGetResponse byte = 0xC0
+	// InsDelete is the instruction byte of the GlobalPlatform command DELETE.
+	InsDelete byte = 0xE4
+	// InsInstall is the instruction byte of the GlobalPlatform command INSTALL.
+	InsInstall byte = 0xE6
+	// InsLoad is the instruction byte of the GlobalPlatform command LOAD.
+	InsLoad byte = 0xE8
+	// InsGetStatus is the instruction byte of the GlobalPlatform command GET STATUS.
+	InsGetStatus byte = 0xF2
+)
+
+const (
+	// InstallForLoad is P1 of INSTALL [for load].
+	InstallForLoad byte = 0x02
+	// InstallForInstall is P1 of INSTALL [for install].
+	InstallForInstall byte = 0x04
+	// InstallForMakeSelectable is P1 of INSTALL [for make selectable].
+	InstallForMakeSelectable byte = 0x08
+	// InstallForInstallAndMakeSelectable is P1 of INSTALL [for install and make selectable].
+	InstallForInstallAndMakeSelectable byte = InstallForInstall | InstallForMakeSelectable
+)
+
+const (
+	// minLenAID is the minimum length of an application identifier (ISO 7816-5).
+	minLenAID int = 5
+	// maxLenAID is the maximum length of an application identifier (ISO 7816-5).
+	maxLenAID int = 16
+)
+
+const (
+	// StatusSubsetISD selects the Issuer Security Domain in GET STATUS.
+	StatusSubsetISD byte = 0x80
+	// StatusSubsetApplications selects Applications, including Security Domains, in GET STATUS.
+	StatusSubsetApplications byte = 0x40
+	// StatusSubsetLoadFiles selects Executable Load Files in GET STATUS.
+	StatusSubsetLoadFiles byte = 0x20
+	// StatusSubsetLoadFilesAndModules selects Executable Load Files and their Executable Modules in GET STATUS.
+	StatusSubsetLoadFilesAndModules byte = 0x10
+)
+
+// GetChallenge returns a GET CHALLENGE command that requests a challenge of the given length in byte.
+// An error is returned if length is not between 1 and MaxLenResponseDataExtended.
+func GetChallenge(length int) (*Capdu, error) {
+	if length < 1 || length > MaxLenResponseDataExtended {
+		return nil, errors.Errorf("%s: invalid challenge length - must be between 1 and %d, got %d", packageTag, MaxLenResponseDataExtended, length)
+	}
+
+	return &Capdu{Cla: 0x00, Ins: InsGetChallenge, P1: 0x00, P2: 0x00, Ne: length}, nil
+}
+
+// ExternalAuthenticate returns an EXTERNAL AUTHENTICATE command with the algorithm reference in p1, the key reference
+// in p2 and a copy of the cryptogram as data.
+// An error is returned if the cryptogram exceeds MaxLenCommandDataStandard.
+func ExternalAuthenticate(p1, p2 byte, cryptogram []byte) (*Capdu, error) {
+	if len(cryptogram) > MaxLenCommandDataStandard {
+		return nil, errors.Errorf("%s: invalid cryptogram length - must not exceed %d byte, got %d", packageTag, MaxLenCommandDataStandard, len(cryptogram))
+	}
+
+	return &Capdu{Cla: 0x00, Ins: InsExternalAuthenticate, P1: p1, P2: p2, Data: append([]byte(nil), cryptogram...)}, nil
+}
+
+// InternalAuthenticate returns an INTERNAL AUTHENTICATE command with the algorithm reference in p1, the key reference
+// in p2, a copy of the challenge as data and ne as expected length of the response.
+// An error is returned if data exceeds MaxLenCommandDataStandard or ne is not between 0 and
+// MaxLenResponseDataStandard.
+func InternalAuthenticate(p1, p2 byte, data []byte, ne int) (*Capdu, error) {
+	if len(data) > MaxLenCommandDataStandard {
+		return nil, errors.Errorf("%s: invalid data length - must not exceed %d byte, got %d", packageTag, MaxLenCommandDataStandard, len(data))
+	}
+
+	if ne < 0 || ne > MaxLenResponseDataStandard {
+		return nil, errors.Errorf("%s: invalid ne - must be between 0 and %d, got %d", packageTag, MaxLenResponseDataStandard, ne)
+	}
+
+	return &Capdu{Cla: 0x00, Ins: InsInternalAuthenticate, P1: p1, P2: p2, Data: append([]byte(nil), data...), Ne: ne}, nil
+}
+
+// NewCommandExpectingMax returns a Case 2 command with the given header that requests the maximum number of response
+// data bytes of the standard length format (Ne 256, encoded as Le '00').
+// Note that a Capdu with Ne 0 is a Case 1 command, which expects no response data at all - Ne must be set explicitly
+// to request response data.
+func NewCommandExpectingMax(cla, ins, p1, p2 byte) *Capdu {
+	return &Capdu{Cla: cla, Ins: ins, P1: p1, P2: p2, Ne: MaxLenResponseDataStandard}
+}
+
+// NewSelectNext returns a SELECT command that selects the next application whose DF name starts with aid
+// (P1 '04', P2 '02') with a copy of aid as data and requests the maximum number of response data bytes of the
+// standard length format (Ne 256).
+func NewSelectNext(aid []byte) *Capdu {
+	return &Capdu{Cla: 0x00, Ins: InsSelect, P1: 0x04, P2: 0x02, Data: append([]byte(nil), aid...), Ne: MaxLenResponseDataStandard}
+}
+
+// NextGetResponse returns the GET RESPONSE command that retrieves the remaining response data and true if the RAPDU
+// indicates that more response data is available ('61xx', see HasMoreData), else nil and false. Ne of the returned
+// command is SW2, where SW2 '00' requests 256 byte. The command uses CLA '00', so the class byte must be adjusted
+// if the preceding command was sent on another logical channel or with secure messaging.
+func NextGetResponse(r *Rapdu) (*Capdu, bool) {
+	if !r.HasMoreData() {
+		return nil, false
+	}
+
+	ne := int(r.SW2)
+	if ne == 0 {
+		ne = MaxLenResponseDataStandard
+	}
+
+	return &Capdu{Cla: 0x00, Ins: InsGetResponse, P1: 0x00, P2: 0x00, Ne: ne}, true
+}
+
+// checkProprietaryClass returns an error if cla is not a proprietary class, which is required by the GlobalPlatform
+// card management commands.
+func checkProprietaryClass(cla byte) error {
+	if !isProprietaryClass(cla) {
+		return errors.Errorf("%s: invalid class 0x%02X - GlobalPlatform commands require a proprietary class", packageTag, cla)
+	}
+
+	return nil
+}
+
+// GetStatus returns a GlobalPlatform GET STATUS command with the given proprietary class, e.g. 0x80 or
+// WithSecureMessaging(0x80), for the given subset (P1) that requests the first or all
+// occurrences in the TLV response format (P2 '02') with a copy of searchCriteria as data and Ne 256.
+// If searchCriteria is empty, the criterion '4F00' is used, which matches all entries of the subset.
+// An error is returned if cla is not a proprietary class, if subset is not one of StatusSubsetISD,
+// StatusSubsetApplications, StatusSubsetLoadFiles and StatusSubsetLoadFilesAndModules or if searchCriteria exceeds
+// MaxLenCommandDataStandard.
+func GetStatus(cla, subset byte, searchCriteria []byte) (*Capdu, error) {
+	if err := checkProprietaryClass(cla); err != nil {
+		return nil, err
+	}
+
+	switch subset {
+	case StatusSubsetISD, StatusSubsetApplications, StatusSubsetLoadFiles, StatusSubsetLoadFilesAndModules:
+	default:
+		return nil, errors.Errorf("%s: invalid GET STATUS subset 0x%02X", packageTag, subset)
+	}
+
+	if len(searchCriteria) > MaxLenCommandDataStandard {
+		return nil, errors.Errorf("%s: invalid search criteria length - must not exceed %d byte, got %d", packageTag, MaxLenCommandDataStandard, len(searchCriteria))
+	}
+
+	if len(searchCriteria) == 0 {
+		searchCriteria = []byte{0x4F, 0x00}
+	}
+
+	return &Capdu{Cla: cla, Ins: InsGetStatus, P1: subset, P2: 0x02, Data: append([]byte(nil), searchCriteria...), Ne: MaxLenResponseDataStandard}, nil
+}
+
+// Delete returns a GlobalPlatform DELETE command with the given proprietary class that deletes the Executable Load
+// File, Application or Security Domain with the given AID and requests Ne 256. If related is true, P2 is set to '80'
+// to delete related objects as well, e.g. the Applications of an Executable Load File.
+// An error is returned if cla is not a proprietary class or if the length of aid is not between 5 and 16 byte.
+func Delete(cla byte, aid []byte, related bool) (*Capdu, error) {
+	if err := checkProprietaryClass(cla); err != nil {
+		return nil, err
+	}
+
+	if len(aid) < minLenAID || len(aid) > maxLenAID {
+		return nil, errors.Errorf("%s: invalid AID length - must be between %d and %d byte, got %d", packageTag, minLenAID, maxLenAID, len(aid))
+	}
+
+	var p2 byte
+	if related {
+		p2 = 0x80
+	}
+
+	data := make([]byte, 0, 2+len(aid))
+	data = append(data, 0x4F, byte(len(aid)))
+	data = append(data, aid...)
+
+	return &Capdu{Cla: cla, Ins: InsDelete, P1: 0x00, P2: p2, Data: data, Ne: MaxLenResponseDataStandard}, nil
+}
+
+// Install returns a GlobalPlatform INSTALL command with the given proprietary class and P1, e.g.
+// InstallForInstallAndMakeSelectable, and a copy of the install parameters as data that requests Ne 256.
+// An error is returned if cla is not a proprietary class or if data is empty or exceeds MaxLenCommandDataStandard.
+func Install(cla, p1 byte, data []byte) (*Capdu, error) {
+	if err := checkProprietaryClass(cla); err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 || len(data) > MaxLenCommandDataStandard {
+		return nil, errors.Errorf("%s: invalid install data length - must be between 1 and %d byte, got %d", packageTag, MaxLenCommandDataStandard, len(data))
+	}
+
+	return &Capdu{Cla: cla, Ins: InsInstall, P1: p1, P2: 0x00, Data: append([]byte(nil), data...), Ne: MaxLenResponseDataStandard}, nil
+}
+
+// maxLoadBlocks is the maximum number of LOAD commands, limited by the block number in P2.
+const maxLoadBlocks int = 256
+
+// LoadBlocks splits the Load File data into GlobalPlatform LOAD commands with the given proprietary class of up to
+// blockSize byte of data each, which request Ne 256. P2 contains the block number starting with 0 and P1 indicates the last block ('80'), while P1 is
+// '00' for all other blocks. The Data of each LOAD command is a copy of the respective block of data.
+// An error is returned if cla is not a proprietary class, if data is empty, if blockSize is not between 1 and
+// MaxLenCommandDataStandard or if more than 256 blocks would be required.
+func LoadBlocks(cla byte, data []byte, blockSize int) ([]*Capdu, error) {
+	if err := checkProprietaryClass(cla); err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return nil, errors.Errorf("%s: load file data must not be empty", packageTag)
+	}
+
+	if blockSize < 1 || blockSize > MaxLenCommandDataStandard {
+		return nil, errors.Errorf("%s: invalid block size - must be between 1 and %d, got %d", packageTag, MaxLenCommandDataStandard, blockSize)
+	}
+
+	numBlocks, err := ChainCount(len(data), blockSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if numBlocks > maxLoadBlocks {
+		return nil, errors.Errorf("%s: load file data of %d byte requires %d blocks of %d byte, but the block number allows at most %d", packageTag, len(data), numBlocks, blockSize, maxLoadBlocks)
+	}
+
+	cmds := make([]*Capdu, 0, numBlocks)
+
+	for i := 0; i < numBlocks; i++ {
+		end := (i + 1) * blockSize
+		if end > len(data) {
+			end = len(data)
+		}
+
+		var p1 byte
+		if i == numBlocks-1 {
+			p1 = 0x80
+		}
+
+		cmds = append(cmds, &Capdu{Cla: cla, Ins: InsLoad, P1: p1, P2: byte(i), Data: append([]byte(nil), data[i*blockSize:end]...), Ne: MaxLenResponseDataStandard})
+	}
+
+	return cmds, nil
+}
@@ -0,0 +1,744 @@
+package apdu
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetChallenge(t *testing.T) {
+	tests := []struct {
+		name    string
+		length  int
+		want    []byte
+		wantErr bool
+	}{
+		{
+			name:    "8 byte challenge",
+			length:  8,
+			want:    []byte{0x00, 0x84, 0x00, 0x00, 0x08},
+			wantErr: false,
+		},
+		{
+			name:    "256 byte challenge",
+			length:  256,
+			want:    []byte{0x00, 0x84, 0x00, 0x00, 0x00},
+			wantErr: false,
+		},
+		{
+			name:    "extended length challenge",
+			length:  257,
+			want:    []byte{0x00, 0x84, 0x00, 0x00, 0x00, 0x01, 0x01},
+			wantErr: false,
+		},
+		{
+			name:    "error: zero length",
+			length:  0,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: length too big",
+			length:  65537,
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetChallenge(tt.length)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetChallenge() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			b, err := got.Bytes()
+			if err != nil {
+				t.Fatalf("Bytes() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(b, tt.want) {
+				t.Errorf("GetChallenge() got = %X, want %X", b, tt.want)
+			}
+		})
+	}
+}
+
+func TestExternalAuthenticate(t *testing.T) {
+	type args struct {
+		p1         byte
+		p2         byte
+		cryptogram []byte
+	}
+
+	tests := []struct {
+		name    string
+		args    args
+		want    []byte
+		wantErr bool
+	}{
+		{
+			name:    "cryptogram",
+			args:    args{p1: 0x00, p2: 0x81, cryptogram: []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}},
+			want:    []byte{0x00, 0x82, 0x00, 0x81, 0x08, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+			wantErr: false,
+		},
+		{
+			name:    "error: cryptogram too long",
+			args:    args{p1: 0x00, p2: 0x81, cryptogram: make([]byte, 256)},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExternalAuthenticate(tt.args.p1, tt.args.p2, tt.args.cryptogram)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ExternalAuthenticate() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			b, err := got.Bytes()
+			if err != nil {
+				t.Fatalf("Bytes() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(b, tt.want) {
+				t.Errorf("ExternalAuthenticate() got = %X, want %X", b, tt.want)
+			}
+		})
+	}
+}
+
+func TestExternalAuthenticate_CopiesData(t *testing.T) {
+	cryptogram := []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
+
+	c, err := ExternalAuthenticate(0x00, 0x01, cryptogram)
+	if err != nil {
+		t.Fatalf("ExternalAuthenticate() error = %v", err)
+	}
+
+	cryptogram[0] = 0xFF
+
+	if c.Data[0] != 0x01 {
+		t.Errorf("ExternalAuthenticate() Data = %X, modification of cryptogram must not affect the Capdu", c.Data)
+	}
+}
+
+func TestInternalAuthenticate(t *testing.T) {
+	type args struct {
+		p1   byte
+		p2   byte
+		data []byte
+		ne   int
+	}
+
+	tests := []struct {
+		name    string
+		args    args
+		want    []byte
+		wantErr bool
+	}{
+		{
+			name:    "challenge",
+			args:    args{p1: 0x00, p2: 0x00, data: []byte{0x01, 0x02, 0x03, 0x04}, ne: 256},
+			want:    []byte{0x00, 0x88, 0x00, 0x00, 0x04, 0x01, 0x02, 0x03, 0x04, 0x00},
+			wantErr: false,
+		},
+		{
+			name:    "error: data too long",
+			args:    args{p1: 0x00, p2: 0x00, data: make([]byte, 256), ne: 256},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: ne too big",
+			args:    args{p1: 0x00, p2: 0x00, data: []byte{0x01}, ne: 257},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: negative ne",
+			args:    args{p1: 0x00, p2: 0x00, data: []byte{0x01}, ne: -1},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := InternalAuthenticate(tt.args.p1, tt.args.p2, tt.args.data, tt.args.ne)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("InternalAuthenticate() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			b, err := got.Bytes()
+			if err != nil {
+				t.Fatalf("Bytes() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(b, tt.want) {
+				t.Errorf("InternalAuthenticate() got = %X, want %X", b, tt.want)
+			}
+		})
+	}
+}
+
+func TestInternalAuthenticate_CopiesData(t *testing.T) {
+	data := []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
+
+	c, err := InternalAuthenticate(0x00, 0x01, data, 0)
+	if err != nil {
+		t.Fatalf("InternalAuthenticate() error = %v", err)
+	}
+
+	data[0] = 0xFF
+
+	if c.Data[0] != 0x01 {
+		t.Errorf("InternalAuthenticate() Data = %X, modification of data must not affect the Capdu", c.Data)
+	}
+}
+
+func TestNewCommandExpectingMax(t *testing.T) {
+	got := NewCommandExpectingMax(0x00, 0xCA, 0x9F, 0x7F)
+
+	b, err := got.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes() error = %v", err)
+	}
+
+	want := []byte{0x00, 0xCA, 0x9F, 0x7F, 0x00}
+	if !reflect.DeepEqual(b, want) {
+		t.Errorf("NewCommandExpectingMax() got = %X, want %X", b, want)
+	}
+
+	if got.Ne != MaxLenResponseDataStandard {
+		t.Errorf("NewCommandExpectingMax() got Ne = %d, want %d", got.Ne, MaxLenResponseDataStandard)
+	}
+}
+
+func TestNewSelectNext(t *testing.T) {
+	got := NewSelectNext([]byte{0xA0, 0x00, 0x00, 0x01, 0x51})
+
+	b, err := got.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes() error = %v", err)
+	}
+
+	want := []byte{0x00, 0xA4, 0x04, 0x02, 0x05, 0xA0, 0x00, 0x00, 0x01, 0x51, 0x00}
+	if !reflect.DeepEqual(b, want) {
+		t.Errorf("NewSelectNext() got = %X, want %X", b, want)
+	}
+}
+
+func TestNewSelectNext_CopiesData(t *testing.T) {
+	aid := []byte{0xA0, 0x00, 0x00, 0x01, 0x51}
+
+	c := NewSelectNext(aid)
+	aid[0] = 0xFF
+
+	if c.Data[0] != 0xA0 {
+		t.Errorf("NewSelectNext() Data = %X, modification of aid must not affect the Capdu", c.Data)
+	}
+}
+
+func TestNextGetResponse(t *testing.T) {
+	tests := []struct {
+		name   string
+		rapdu  *Rapdu
+		want   *Capdu
+		wantOk bool
+	}{
+		{
+			name:   "61 10",
+			rapdu:  &Rapdu{SW1: 0x61, SW2: 0x10},
+			want:   &Capdu{Cla: 0x00, Ins: 0xC0, P1: 0x00, P2: 0x00, Ne: 16},
+			wantOk: true,
+		},
+		{
+			name:   "61 00 with data",
+			rapdu:  &Rapdu{Data: []byte{0x01, 0x02}, SW1: 0x61, SW2: 0x00},
+			want:   &Capdu{Cla: 0x00, Ins: 0xC0, P1: 0x00, P2: 0x00, Ne: 256},
+			wantOk: true,
+		},
+		{
+			name:   "90 00",
+			rapdu:  &Rapdu{SW1: 0x90, SW2: 0x00},
+			want:   nil,
+			wantOk: false,
+		},
+		{
+			name:   "6C 10",
+			rapdu:  &Rapdu{SW1: 0x6C, SW2: 0x10},
+			want:   nil,
+			wantOk: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotOk := NextGetResponse(tt.rapdu)
+			if gotOk != tt.wantOk {
+				t.Errorf("NextGetResponse() gotOk = %v, want %v", gotOk, tt.wantOk)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NextGetResponse() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetStatus(t *testing.T) {
+	type args struct {
+		cla            byte
+		subset         byte
+		searchCriteria []byte
+	}
+
+	tests := []struct {
+		name    string
+		args    args
+		want    []byte
+		wantErr bool
+	}{
+		{
+			name:    "applications, all",
+			args:    args{cla: 0x80, subset: StatusSubsetApplications, searchCriteria: []byte{0x4F, 0x00}},
+			want:    []byte{0x80, 0xF2, 0x40, 0x02, 0x02, 0x4F, 0x00, 0x00},
+			wantErr: false,
+		},
+		{
+			name:    "ISD, default search criteria",
+			args:    args{cla: 0x80, subset: StatusSubsetISD, searchCriteria: nil},
+			want:    []byte{0x80, 0xF2, 0x80, 0x02, 0x02, 0x4F, 0x00, 0x00},
+			wantErr: false,
+		},
+		{
+			name:    "load files, AID",
+			args:    args{cla: 0x80, subset: StatusSubsetLoadFiles, searchCriteria: []byte{0x4F, 0x05, 0xA0, 0x00, 0x00, 0x01, 0x51}},
+			want:    []byte{0x80, 0xF2, 0x20, 0x02, 0x07, 0x4F, 0x05, 0xA0, 0x00, 0x00, 0x01, 0x51, 0x00},
+			wantErr: false,
+		},
+		{
+			name:    "load files and modules",
+			args:    args{cla: 0x80, subset: StatusSubsetLoadFilesAndModules},
+			want:    []byte{0x80, 0xF2, 0x10, 0x02, 0x02, 0x4F, 0x00, 0x00},
+			wantErr: false,
+		},
+		{
+			name:    "secure messaging",
+			args:    args{cla: WithSecureMessaging(0x80), subset: StatusSubsetApplications},
+			want:    []byte{0x84, 0xF2, 0x40, 0x02, 0x02, 0x4F, 0x00, 0x00},
+			wantErr: false,
+		},
+		{
+			name:    "error: interindustry class",
+			args:    args{cla: 0x00, subset: StatusSubsetApplications},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: invalid subset",
+			args:    args{cla: 0x80, subset: 0x08},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: search criteria too long",
+			args:    args{cla: 0x80, subset: StatusSubsetApplications, searchCriteria: make([]byte, 256)},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetStatus(tt.args.cla, tt.args.subset, tt.args.searchCriteria)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetStatus() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			b, err := got.Bytes()
+			if err != nil {
+				t.Fatalf("Bytes() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(b, tt.want) {
+				t.Errorf("GetStatus() got = %X, want %X", b, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetStatus_CopiesData(t *testing.T) {
+	searchCriteria := []byte{0x4F, 0x05, 0xA0, 0x00, 0x00, 0x01, 0x51}
+
+	c, err := GetStatus(0x80, StatusSubsetApplications, searchCriteria)
+	if err != nil {
+		t.Fatalf("GetStatus() error = %v", err)
+	}
+
+	searchCriteria[0] = 0xFF
+
+	if c.Data[0] != 0x4F {
+		t.Errorf("GetStatus() Data = %X, modification of searchCriteria must not affect the Capdu", c.Data)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	type args struct {
+		cla     byte
+		aid     []byte
+		related bool
+	}
+
+	tests := []struct {
+		name    string
+		args    args
+		want    []byte
+		wantErr bool
+	}{
+		{
+			name:    "delete object",
+			args:    args{cla: 0x80, aid: []byte{0xA0, 0x00, 0x00, 0x01, 0x51, 0x00, 0x01}, related: false},
+			want:    []byte{0x80, 0xE4, 0x00, 0x00, 0x09, 0x4F, 0x07, 0xA0, 0x00, 0x00, 0x01, 0x51, 0x00, 0x01, 0x00},
+			wantErr: false,
+		},
+		{
+			name:    "delete object and related objects",
+			args:    args{cla: 0x80, aid: []byte{0xA0, 0x00, 0x00, 0x01, 0x51}, related: true},
+			want:    []byte{0x80, 0xE4, 0x00, 0x80, 0x07, 0x4F, 0x05, 0xA0, 0x00, 0x00, 0x01, 0x51, 0x00},
+			wantErr: false,
+		},
+		{
+			name:    "secure messaging",
+			args:    args{cla: WithSecureMessaging(0x80), aid: []byte{0xA0, 0x00, 0x00, 0x01, 0x51}, related: false},
+			want:    []byte{0x84, 0xE4, 0x00, 0x00, 0x07, 0x4F, 0x05, 0xA0, 0x00, 0x00, 0x01, 0x51, 0x00},
+			wantErr: false,
+		},
+		{
+			name:    "error: interindustry class",
+			args:    args{cla: 0x00, aid: []byte{0xA0, 0x00, 0x00, 0x01, 0x51}},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: AID too short",
+			args:    args{cla: 0x80, aid: []byte{0xA0, 0x00, 0x00, 0x01}},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: AID too long",
+			args:    args{cla: 0x80, aid: make([]byte, 17)},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Delete(tt.args.cla, tt.args.aid, tt.args.related)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Delete() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			b, err := got.Bytes()
+			if err != nil {
+				t.Fatalf("Bytes() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(b, tt.want) {
+				t.Errorf("Delete() got = %X, want %X", b, tt.want)
+			}
+		})
+	}
+}
+
+func TestDelete_CopiesData(t *testing.T) {
+	aid := []byte{0xA0, 0x00, 0x00, 0x01, 0x51}
+
+	c, err := Delete(0x80, aid, false)
+	if err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	aid[0] = 0xFF
+
+	if c.Data[2] != 0xA0 {
+		t.Errorf("Delete() Data = %X, modification of aid must not affect the Capdu", c.Data)
+	}
+}
+
+func TestInstall(t *testing.T) {
+	installData := []byte{
+		0x05, 0xA0, 0x00, 0x00, 0x01, 0x51, // Executable Load File AID
+		0x06, 0xA0, 0x00, 0x00, 0x01, 0x51, 0x01, // Executable Module AID
+		0x06, 0xA0, 0x00, 0x00, 0x01, 0x51, 0x01, // Application AID
+		0x01, 0x00, // privileges
+		0x02, 0xC9, 0x00, // install parameters
+		0x00, // install token
+	}
+
+	type args struct {
+		cla  byte
+		p1   byte
+		data []byte
+	}
+
+	tests := []struct {
+		name    string
+		args    args
+		want    []byte
+		wantErr bool
+	}{
+		{
+			name:    "for install and make selectable",
+			args:    args{cla: 0x80, p1: InstallForInstallAndMakeSelectable, data: installData},
+			want:    append(append([]byte{0x80, 0xE6, 0x0C, 0x00, 0x1A}, installData...), 0x00),
+			wantErr: false,
+		},
+		{
+			name:    "for load",
+			args:    args{cla: 0x80, p1: InstallForLoad, data: []byte{0x05, 0xA0, 0x00, 0x00, 0x01, 0x51, 0x00, 0x00, 0x00, 0x00}},
+			want:    []byte{0x80, 0xE6, 0x02, 0x00, 0x0A, 0x05, 0xA0, 0x00, 0x00, 0x01, 0x51, 0x00, 0x00, 0x00, 0x00, 0x00},
+			wantErr: false,
+		},
+		{
+			name:    "secure messaging",
+			args:    args{cla: WithSecureMessaging(0x80), p1: InstallForLoad, data: []byte{0x05, 0xA0, 0x00, 0x00, 0x01, 0x51, 0x00, 0x00, 0x00, 0x00}},
+			want:    []byte{0x84, 0xE6, 0x02, 0x00, 0x0A, 0x05, 0xA0, 0x00, 0x00, 0x01, 0x51, 0x00, 0x00, 0x00, 0x00, 0x00},
+			wantErr: false,
+		},
+		{
+			name:    "error: interindustry class",
+			args:    args{cla: 0x00, p1: InstallForLoad, data: []byte{0x05, 0xA0, 0x00, 0x00, 0x01, 0x51, 0x00, 0x00, 0x00, 0x00}},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: empty data",
+			args:    args{cla: 0x80, p1: InstallForInstall, data: nil},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: data too long",
+			args:    args{cla: 0x80, p1: InstallForInstall, data: make([]byte, 256)},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Install(tt.args.cla, tt.args.p1, tt.args.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Install() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			b, err := got.Bytes()
+			if err != nil {
+				t.Fatalf("Bytes() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(b, tt.want) {
+				t.Errorf("Install() got = %X, want %X", b, tt.want)
+			}
+		})
+	}
+}
+
+func TestInstall_CopiesData(t *testing.T) {
+	data := []byte{0x05, 0xA0, 0x00, 0x00, 0x01, 0x51, 0x00, 0x00, 0x00, 0x00}
+
+	c, err := Install(0x80, InstallForLoad, data)
+	if err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+
+	data[1] = 0xFF
+
+	if c.Data[1] != 0xA0 {
+		t.Errorf("Install() Data = %X, modification of data must not affect the Capdu", c.Data)
+	}
+}
+
+func TestLoadBlocks(t *testing.T) {
+	data := make([]byte, 10)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	type args struct {
+		cla       byte
+		data      []byte
+		blockSize int
+	}
+
+	tests := []struct {
+		name    string
+		args    args
+		want    []*Capdu
+		wantErr bool
+	}{
+		{
+			name: "single block",
+			args: args{cla: 0x80, data: data, blockSize: 10},
+			want: []*Capdu{
+				{Cla: 0x80, Ins: 0xE8, P1: 0x80, P2: 0x00, Data: data, Ne: 256},
+			},
+			wantErr: false,
+		},
+		{
+			name: "multiple blocks with short last block",
+			args: args{cla: 0x80, data: data, blockSize: 4},
+			want: []*Capdu{
+				{Cla: 0x80, Ins: 0xE8, P1: 0x00, P2: 0x00, Data: []byte{0x00, 0x01, 0x02, 0x03}, Ne: 256},
+				{Cla: 0x80, Ins: 0xE8, P1: 0x00, P2: 0x01, Data: []byte{0x04, 0x05, 0x06, 0x07}, Ne: 256},
+				{Cla: 0x80, Ins: 0xE8, P1: 0x80, P2: 0x02, Data: []byte{0x08, 0x09}, Ne: 256},
+			},
+			wantErr: false,
+		},
+		{
+			name: "multiple blocks of equal size",
+			args: args{cla: 0x80, data: data, blockSize: 5},
+			want: []*Capdu{
+				{Cla: 0x80, Ins: 0xE8, P1: 0x00, P2: 0x00, Data: []byte{0x00, 0x01, 0x02, 0x03, 0x04}, Ne: 256},
+				{Cla: 0x80, Ins: 0xE8, P1: 0x80, P2: 0x01, Data: []byte{0x05, 0x06, 0x07, 0x08, 0x09}, Ne: 256},
+			},
+			wantErr: false,
+		},
+		{
+			name: "secure messaging",
+			args: args{cla: WithSecureMessaging(0x80), data: data, blockSize: 10},
+			want: []*Capdu{
+				{Cla: 0x84, Ins: 0xE8, P1: 0x80, P2: 0x00, Data: data, Ne: 256},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "error: interindustry class",
+			args:    args{cla: 0x00, data: data, blockSize: 10},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: empty data",
+			args:    args{cla: 0x80, data: nil, blockSize: 4},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: block size zero",
+			args:    args{cla: 0x80, data: data, blockSize: 0},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: block size too large",
+			args:    args{cla: 0x80, data: data, blockSize: 256},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: too many blocks",
+			args:    args{cla: 0x80, data: make([]byte, 257), blockSize: 1},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadBlocks(tt.args.cla, tt.args.data, tt.args.blockSize)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadBlocks() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadBlocks() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadBlocks_CopiesData(t *testing.T) {
+	data := []byte{0x01, 0x02, 0x03, 0x04, 0x05}
+
+	cmds, err := LoadBlocks(0x80, data, 2)
+	if err != nil {
+		t.Fatalf("LoadBlocks() error = %v", err)
+	}
+
+	data[0] = 0xFF
+
+	if cmds[0].Data[0] != 0x01 {
+		t.Errorf("LoadBlocks() Data = %X, modification of data must not affect the Capdu", cmds[0].Data)
+	}
+
+	cmds[0].Data = append(cmds[0].Data, 0xEE)
+
+	if cmds[1].Data[0] != 0x03 {
+		t.Errorf("LoadBlocks() Data = %X, appending to a block must not affect the next block", cmds[1].Data)
+	}
+}
+
+func TestLoadBlocks_MaxBlocks(t *testing.T) {
+	got, err := LoadBlocks(0x80, make([]byte, 256), 1)
+	if err != nil {
+		t.Fatalf("LoadBlocks() error = %v", err)
+	}
+
+	if len(got) != 256 {
+		t.Fatalf("LoadBlocks() got %d blocks, want 256", len(got))
+	}
+
+	for i, c := range got[:255] {
+		if c.P1 != 0x00 || c.P2 != byte(i) {
+			t.Errorf("LoadBlocks() block %d got P1P2 %02X%02X, want 00%02X", i, c.P1, c.P2, i)
+		}
+	}
+
+	if last := got[255]; last.P1 != 0x80 || last.P2 != 0xFF {
+		t.Errorf("LoadBlocks() last block got P1P2 %02X%02X, want 80FF", last.P1, last.P2)
+	}
+}
@@ -0,0 +1,104 @@
+package apdu
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// CompareCapdu compares expected and actual according to Capdu.Equal and returns true and an empty string if they are
+// equal. Otherwise false is returned together with a multi-line description of the differing fields that is suitable
+// for test failure messages, e.g.:
+//
+//	Capdu mismatch:
+//	  P1: expected 04, got 00
+func CompareCapdu(expected, actual *Capdu) (bool, string) {
+	if expected.Equal(actual) {
+		return true, ""
+	}
+
+	if expected == nil || actual == nil {
+		return false, fmt.Sprintf("Capdu mismatch:\n  expected %s, got %s\n", describeCapdu(expected), describeCapdu(actual))
+	}
+
+	sb := &strings.Builder{}
+	sb.WriteString("Capdu mismatch:\n")
+
+	writeByteDiff(sb, "CLA", expected.Cla, actual.Cla)
+	writeByteDiff(sb, "INS", expected.Ins, actual.Ins)
+	writeByteDiff(sb, "P1", expected.P1, actual.P1)
+	writeByteDiff(sb, "P2", expected.P2, actual.P2)
+	writeDataDiff(sb, expected.Data, actual.Data)
+
+	if expected.Ne != actual.Ne {
+		fmt.Fprintf(sb, "  Ne: expected %d, got %d\n", expected.Ne, actual.Ne)
+	}
+
+	return false, sb.String()
+}
+
+// CompareRapdu compares expected and actual and returns true and an empty string if they have the same status word
+// and data. Nil and empty data are considered equal. Otherwise false is returned together with a multi-line
+// description of the differing fields that describes the status words according to LookupStatusWord, e.g.:
+//
+//	Rapdu mismatch:
+//	  SW: expected 9000 (Normal processing, no further qualification), got 6A82 (Wrong parameters, file or application not found)
+func CompareRapdu(expected, actual *Rapdu) (bool, string) {
+	if expected == nil || actual == nil {
+		if expected == nil && actual == nil {
+			return true, ""
+		}
+
+		return false, fmt.Sprintf("Rapdu mismatch:\n  expected %s, got %s\n", describeRapdu(expected), describeRapdu(actual))
+	}
+
+	if expected.sw() == actual.sw() && bytes.Equal(expected.Data, actual.Data) {
+		return true, ""
+	}
+
+	sb := &strings.Builder{}
+	sb.WriteString("Rapdu mismatch:\n")
+
+	writeDataDiff(sb, expected.Data, actual.Data)
+
+	if expected.sw() != actual.sw() {
+		fmt.Fprintf(sb, "  SW: expected %04X (%s), got %04X (%s)\n",
+			expected.sw(), describeStatusWord(expected.sw()), actual.sw(), describeStatusWord(actual.sw()))
+	}
+
+	return false, sb.String()
+}
+
+// writeByteDiff writes a line that describes the difference of a byte field if expected and actual differ.
+func writeByteDiff(sb *strings.Builder, name string, expected, actual byte) {
+	if expected != actual {
+		fmt.Fprintf(sb, "  %s: expected %02X, got %02X\n", name, expected, actual)
+	}
+}
+
+// writeDataDiff writes a line that describes the difference of the data fields if expected and actual differ.
+func writeDataDiff(sb *strings.Builder, expected, actual []byte) {
+	if !bytes.Equal(expected, actual) {
+		fmt.Fprintf(sb, "  Data: expected %s (%d byte), got %s (%d byte)\n",
+			strings.ToUpper(hex.EncodeToString(expected)), len(expected), strings.ToUpper(hex.EncodeToString(actual)), len(actual))
+	}
+}
+
+// describeCapdu returns a short description of c in the format "CLAINSP1P2/DATA/Ne" or "<nil>".
+func describeCapdu(c *Capdu) string {
+	if c == nil {
+		return "<nil>"
+	}
+
+	return fmt.Sprintf("%02X%02X%02X%02X/%s/%d", c.Cla, c.Ins, c.P1, c.P2, strings.ToUpper(hex.EncodeToString(c.Data)), c.Ne)
+}
+
+// describeRapdu returns the hex encoded data and status word of r or "<nil>".
+func describeRapdu(r *Rapdu) string {
+	if r == nil {
+		return "<nil>"
+	}
+
+	return fmt.Sprintf("%s%04X", strings.ToUpper(hex.EncodeToString(r.Data)), r.sw())
+}
@@ -0,0 +1,114 @@
+package apdu
+
+import "testing"
+
+func TestCompareCapdu(t *testing.T) {
+	tests := []struct {
+		name      string
+		expected  *Capdu
+		actual    *Capdu
+		wantEqual bool
+		wantDiff  string
+	}{
+		{
+			name:      "equal",
+			expected:  &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0xA0, 0x00}, Ne: 256},
+			actual:    &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0xA0, 0x00}, Ne: 256},
+			wantEqual: true,
+			wantDiff:  "",
+		},
+		{
+			name:      "P1 mismatch",
+			expected:  &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0xA0, 0x00}, Ne: 256},
+			actual:    &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x00, P2: 0x00, Data: []byte{0xA0, 0x00}, Ne: 256},
+			wantEqual: false,
+			wantDiff:  "Capdu mismatch:\n  P1: expected 04, got 00\n",
+		},
+		{
+			name:      "multiple mismatches",
+			expected:  &Capdu{Cla: 0x80, Ins: 0xCA, P1: 0x00, P2: 0x66, Data: []byte{0x01}, Ne: 256},
+			actual:    &Capdu{Cla: 0x84, Ins: 0xCA, P1: 0x00, P2: 0x66, Data: []byte{0x01, 0x02}, Ne: 0},
+			wantEqual: false,
+			wantDiff:  "Capdu mismatch:\n  CLA: expected 80, got 84\n  Data: expected 01 (1 byte), got 0102 (2 byte)\n  Ne: expected 256, got 0\n",
+		},
+		{
+			name:      "nil actual",
+			expected:  &Capdu{Cla: 0x00, Ins: 0xB0, Ne: 16},
+			actual:    nil,
+			wantEqual: false,
+			wantDiff:  "Capdu mismatch:\n  expected 00B00000//16, got <nil>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotEqual, gotDiff := CompareCapdu(tt.expected, tt.actual)
+			if gotEqual != tt.wantEqual {
+				t.Errorf("CompareCapdu() gotEqual = %v, want %v", gotEqual, tt.wantEqual)
+			}
+
+			if gotDiff != tt.wantDiff {
+				t.Errorf("CompareCapdu() gotDiff = %q, want %q", gotDiff, tt.wantDiff)
+			}
+		})
+	}
+}
+
+func TestCompareRapdu(t *testing.T) {
+	tests := []struct {
+		name      string
+		expected  *Rapdu
+		actual    *Rapdu
+		wantEqual bool
+		wantDiff  string
+	}{
+		{
+			name:      "equal",
+			expected:  &Rapdu{Data: []byte{0x01}, SW1: 0x90, SW2: 0x00},
+			actual:    &Rapdu{Data: []byte{0x01}, SW1: 0x90, SW2: 0x00},
+			wantEqual: true,
+			wantDiff:  "",
+		},
+		{
+			name:      "nil and empty data",
+			expected:  &Rapdu{SW1: 0x90, SW2: 0x00},
+			actual:    &Rapdu{Data: []byte{}, SW1: 0x90, SW2: 0x00},
+			wantEqual: true,
+			wantDiff:  "",
+		},
+		{
+			name:      "SW mismatch",
+			expected:  &Rapdu{SW1: 0x90, SW2: 0x00},
+			actual:    &Rapdu{SW1: 0x6A, SW2: 0x82},
+			wantEqual: false,
+			wantDiff:  "Rapdu mismatch:\n  SW: expected 9000 (Normal processing, no further qualification), got 6A82 (Wrong parameters, file or application not found)\n",
+		},
+		{
+			name:      "data mismatch",
+			expected:  &Rapdu{Data: []byte{0x01}, SW1: 0x90, SW2: 0x00},
+			actual:    &Rapdu{Data: []byte{0x02}, SW1: 0x90, SW2: 0x00},
+			wantEqual: false,
+			wantDiff:  "Rapdu mismatch:\n  Data: expected 01 (1 byte), got 02 (1 byte)\n",
+		},
+		{
+			name:      "nil expected",
+			expected:  nil,
+			actual:    &Rapdu{SW1: 0x90, SW2: 0x00},
+			wantEqual: false,
+			wantDiff:  "Rapdu mismatch:\n  expected <nil>, got 9000\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotEqual, gotDiff := CompareRapdu(tt.expected, tt.actual)
+			if gotEqual != tt.wantEqual {
+				t.Errorf("CompareRapdu() gotEqual = %v, want %v", gotEqual, tt.wantEqual)
+			}
+
+			if gotDiff != tt.wantDiff {
+				t.Errorf("CompareRapdu() gotDiff = %q, want %q", gotDiff, tt.wantDiff)
+			}
+		})
+	}
+}
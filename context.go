@@ -0,0 +1,19 @@
+package apdu
+
+import "context"
+
+// capduContextKey is the type of the key that is used to store a Capdu in a context.Context.
+type capduContextKey struct{}
+
+// WithCapdu returns a copy of ctx that carries c. This allows e.g. logging or retry layers that wrap the transmission
+// of a command to access the current Capdu.
+func WithCapdu(ctx context.Context, c *Capdu) context.Context {
+	return context.WithValue(ctx, capduContextKey{}, c)
+}
+
+// CapduFromContext returns the Capdu that is carried by ctx and true, or nil and false if ctx carries no Capdu.
+func CapduFromContext(ctx context.Context) (*Capdu, bool) {
+	c, ok := ctx.Value(capduContextKey{}).(*Capdu)
+
+	return c, ok
+}
@@ -0,0 +1,56 @@
+package apdu
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestCapduFromContext(t *testing.T) {
+	select01 := &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0x01, 0x02}, Ne: 256}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		want   *Capdu
+		wantOk bool
+	}{
+		{
+			name:   "set",
+			ctx:    WithCapdu(context.Background(), select01),
+			want:   select01,
+			wantOk: true,
+		},
+		{
+			name:   "overwritten",
+			ctx:    WithCapdu(WithCapdu(context.Background(), &Capdu{Ins: 0xC0}), select01),
+			want:   select01,
+			wantOk: true,
+		},
+		{
+			name:   "missing",
+			ctx:    context.Background(),
+			want:   nil,
+			wantOk: false,
+		},
+		{
+			name:   "other value with same underlying key type",
+			ctx:    context.WithValue(context.Background(), struct{}{}, select01),
+			want:   nil,
+			wantOk: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotOk := CapduFromContext(tt.ctx)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CapduFromContext() got = %v, want %v", got, tt.want)
+			}
+
+			if gotOk != tt.wantOk {
+				t.Errorf("CapduFromContext() gotOk = %v, want %v", gotOk, tt.wantOk)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package apdu
+
+import (
+	"github.com/pkg/errors"
+)
+
+const (
+	// TagPINTryCounter is the tag of the PIN try counter data object.
+	TagPINTryCounter uint32 = 0x9F17
+	// TagKeyInfoTemplate is the tag of the GlobalPlatform Key Information Template.
+	TagKeyInfoTemplate uint32 = 0xE0
+	// TagKeyInfoData is the tag of the Key Information Data in the GlobalPlatform Key Information Template.
+	TagKeyInfoData uint32 = 0xC0
+)
+
+// keyTypeExtended indicates the extended format of a key type in the Key Information Data, which is not supported.
+const keyTypeExtended byte = 0xFF
+
+// KeyInfo is the information about a key of a GlobalPlatform Key Information Template.
+type KeyInfo struct {
+	Identifier byte // Identifier is the key identifier.
+	Version    byte // Version is the key version number.
+	Type       byte // Type is the key type, e.g. '80' for DES or '88' for AES.
+	Length     int  // Length is the length of the key in byte.
+}
+
+// ParsePINTryCounter parses the BER-TLV encoded response data of GET DATA for the PIN try counter (tag '9F17') and
+// returns the number of remaining PIN tries.
+func ParsePINTryCounter(data []byte) (int, error) {
+	t, ok, err := findTLV(data, TagPINTryCounter)
+	if err != nil {
+		return 0, errors.Wrapf(err, "%s: invalid PIN try counter data", packageTag)
+	}
+
+	if !ok {
+		return 0, errors.Errorf("%s: PIN try counter (tag %X) not present", packageTag, TagPINTryCounter)
+	}
+
+	if len(t.value) != 1 {
+		return 0, errors.Errorf("%s: invalid length of PIN try counter - must be 1 byte, got %d", packageTag, len(t.value))
+	}
+
+	return int(t.value[0]), nil
+}
+
+// ParseKeyInfoTemplate parses the BER-TLV encoded response data of GET DATA for the GlobalPlatform Key Information
+// Template (tag 'E0') and returns the information of each contained Key Information Data (tag 'C0'). If a key
+// consists of several components, the type and length of the first component are returned.
+// An empty slice is returned if the template is absent and an error if the template is malformed or uses the
+// extended format for key types.
+func ParseKeyInfoTemplate(data []byte) ([]KeyInfo, error) {
+	t, ok, err := findTLV(data, TagKeyInfoTemplate)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s: invalid key information template data", packageTag)
+	}
+
+	if !ok {
+		return []KeyInfo{}, nil
+	}
+
+	tlvs, err := parseTLVs(t.value)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s: invalid key information template", packageTag)
+	}
+
+	keys := make([]KeyInfo, 0, len(tlvs))
+
+	for _, kid := range tlvs {
+		if kid.tag != TagKeyInfoData {
+			continue
+		}
+
+		// key identifier and key version number are followed by pairs of key type and key length
+		if len(kid.value) < 4 || len(kid.value)%2 != 0 {
+			return nil, errors.Errorf("%s: invalid length of key information data - must be at least 4 byte and even, got %d", packageTag, len(kid.value))
+		}
+
+		if kid.value[2] == keyTypeExtended {
+			return nil, errors.Errorf("%s: extended format of key information data is not supported", packageTag)
+		}
+
+		keys = append(keys, KeyInfo{
+			Identifier: kid.value[0],
+			Version:    kid.value[1],
+			Type:       kid.value[2],
+			Length:     int(kid.value[3]),
+		})
+	}
+
+	return keys, nil
+}
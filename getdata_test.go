@@ -0,0 +1,140 @@
+package apdu
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePINTryCounter(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    int
+		wantErr bool
+	}{
+		{
+			name:    "PIN try counter",
+			data:    []byte{0x9F, 0x17, 0x01, 0x03},
+			want:    3,
+			wantErr: false,
+		},
+		{
+			name:    "PIN try counter in template",
+			data:    []byte{0x70, 0x04, 0x9F, 0x17, 0x01, 0x00},
+			want:    0,
+			wantErr: false,
+		},
+		{
+			name:    "error: tag absent",
+			data:    []byte{0x9F, 0x36, 0x02, 0x00, 0x01},
+			want:    0,
+			wantErr: true,
+		},
+		{
+			name:    "error: invalid value length",
+			data:    []byte{0x9F, 0x17, 0x02, 0x00, 0x03},
+			want:    0,
+			wantErr: true,
+		},
+		{
+			name:    "error: malformed TLV",
+			data:    []byte{0x9F, 0x17, 0x02, 0x03},
+			want:    0,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePINTryCounter(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParsePINTryCounter() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParsePINTryCounter() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseKeyInfoTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    []KeyInfo
+		wantErr bool
+	}{
+		{
+			name: "SCP03 key set",
+			data: []byte{
+				0xE0, 0x12,
+				0xC0, 0x04, 0x01, 0x30, 0x88, 0x10,
+				0xC0, 0x04, 0x02, 0x30, 0x88, 0x10,
+				0xC0, 0x04, 0x03, 0x30, 0x88, 0x10,
+			},
+			want: []KeyInfo{
+				{Identifier: 0x01, Version: 0x30, Type: 0x88, Length: 16},
+				{Identifier: 0x02, Version: 0x30, Type: 0x88, Length: 16},
+				{Identifier: 0x03, Version: 0x30, Type: 0x88, Length: 16},
+			},
+			wantErr: false,
+		},
+		{
+			name: "key with several components",
+			data: []byte{
+				0xE0, 0x08,
+				0xC0, 0x06, 0x01, 0x01, 0xA1, 0x80, 0xA0, 0x03,
+			},
+			want: []KeyInfo{
+				{Identifier: 0x01, Version: 0x01, Type: 0xA1, Length: 128},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "template absent",
+			data:    []byte{0x66, 0x02, 0x73, 0x00},
+			want:    []KeyInfo{},
+			wantErr: false,
+		},
+		{
+			name:    "empty template",
+			data:    []byte{0xE0, 0x00},
+			want:    []KeyInfo{},
+			wantErr: false,
+		},
+		{
+			name:    "error: key information data too short",
+			data:    []byte{0xE0, 0x05, 0xC0, 0x03, 0x01, 0x01, 0x80},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: extended format",
+			data:    []byte{0xE0, 0x08, 0xC0, 0x06, 0x01, 0x01, 0xFF, 0x88, 0x00, 0x10},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: malformed TLV",
+			data:    []byte{0xE0, 0x06, 0xC0, 0x04, 0x01},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseKeyInfoTemplate(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseKeyInfoTemplate() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseKeyInfoTemplate() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package apdu
+
+// InsCategory is the category of an instruction.
+type InsCategory int
+
+const (
+	// CategoryOther is the category of unknown instructions and instructions that fit no other category.
+	CategoryOther InsCategory = iota
+	// CategoryRead is the category of instructions that retrieve data without changing it.
+	CategoryRead
+	// CategoryWrite is the category of instructions that change data.
+	CategoryWrite
+	// CategorySecurity is the category of instructions that authenticate or manage security related data.
+	CategorySecurity
+	// CategoryAdmin is the category of instructions that manage files, applications or the life cycle.
+	CategoryAdmin
+)
+
+// String returns the name of the InsCategory.
+func (i InsCategory) String() string {
+	switch i {
+	case CategoryRead:
+		return "read"
+	case CategoryWrite:
+		return "write"
+	case CategorySecurity:
+		return "security"
+	case CategoryAdmin:
+		return "admin"
+	default:
+		return "other"
+	}
+}
+
+// interindustryCategories maps the instructions of ISO 7816-4, 7816-8 and 7816-9 to their category.
+var interindustryCategories = map[byte]InsCategory{
+	0x04: CategoryAdmin,    // DEACTIVATE FILE
+	0x0C: CategoryWrite,    // ERASE RECORD(S)
+	0x0E: CategoryWrite,    // ERASE BINARY
+	0x0F: CategoryWrite,    // ERASE BINARY
+	0x20: CategorySecurity, // VERIFY
+	0x21: CategorySecurity, // VERIFY
+	0x22: CategorySecurity, // MANAGE SECURITY ENVIRONMENT
+	0x24: CategorySecurity, // CHANGE REFERENCE DATA
+	0x26: CategorySecurity, // DISABLE VERIFICATION REQUIREMENT
+	0x28: CategorySecurity, // ENABLE VERIFICATION REQUIREMENT
+	0x2A: CategorySecurity, // PERFORM SECURITY OPERATION
+	0x2C: CategorySecurity, // RESET RETRY COUNTER
+	0x44: CategoryAdmin,    // ACTIVATE FILE
+	0x82: CategorySecurity, // EXTERNAL AUTHENTICATE
+	0x84: CategorySecurity, // GET CHALLENGE
+	0x86: CategorySecurity, // GENERAL AUTHENTICATE
+	0x87: CategorySecurity, // GENERAL AUTHENTICATE
+	0x88: CategorySecurity, // INTERNAL AUTHENTICATE
+	0xA0: CategoryRead,     // SEARCH BINARY
+	0xA1: CategoryRead,     // SEARCH BINARY
+	0xA2: CategoryRead,     // SEARCH RECORD
+	0xA4: CategoryRead,     // SELECT
+	0xB0: CategoryRead,     // READ BINARY
+	0xB1: CategoryRead,     // READ BINARY
+	0xB2: CategoryRead,     // READ RECORD(S)
+	0xB3: CategoryRead,     // READ RECORD(S)
+	0xC0: CategoryRead,     // GET RESPONSE
+	0xCA: CategoryRead,     // GET DATA
+	0xCB: CategoryRead,     // GET DATA
+	0xD0: CategoryWrite,    // WRITE BINARY
+	0xD1: CategoryWrite,    // WRITE BINARY
+	0xD2: CategoryWrite,    // WRITE RECORD
+	0xD6: CategoryWrite,    // UPDATE BINARY
+	0xD7: CategoryWrite,    // UPDATE BINARY
+	0xDA: CategoryWrite,    // PUT DATA
+	0xDB: CategoryWrite,    // PUT DATA
+	0xDC: CategoryWrite,    // UPDATE RECORD
+	0xDD: CategoryWrite,    // UPDATE RECORD
+	0xE0: CategoryAdmin,    // CREATE FILE
+	0xE2: CategoryWrite,    // APPEND RECORD
+	0xE4: CategoryAdmin,    // DELETE FILE
+	0xE6: CategoryAdmin,    // TERMINATE DF
+	0xE8: CategoryAdmin,    // TERMINATE EF
+	0xFE: CategoryAdmin,    // TERMINATE CARD USAGE
+}
+
+// proprietaryCategories maps the instructions of GlobalPlatform to their category.
+var proprietaryCategories = map[byte]InsCategory{
+	0x50: CategorySecurity, // INITIALIZE UPDATE
+	0x78: CategorySecurity, // END R-MAC SESSION
+	0x7A: CategorySecurity, // BEGIN R-MAC SESSION
+	0x82: CategorySecurity, // EXTERNAL AUTHENTICATE
+	0xCA: CategoryRead,     // GET DATA
+	0xCB: CategoryRead,     // GET DATA
+	0xD8: CategorySecurity, // PUT KEY
+	0xE2: CategoryWrite,    // STORE DATA
+	0xE4: CategoryAdmin,    // DELETE
+	0xE6: CategoryAdmin,    // INSTALL
+	0xE8: CategoryAdmin,    // LOAD
+	0xF0: CategoryAdmin,    // SET STATUS
+	0xF2: CategoryRead,     // GET STATUS
+}
+
+// InstructionCategory returns the category of the instruction of the Capdu. Instructions of interindustry classes
+// are categorized according to ISO 7816, instructions of proprietary classes according to GlobalPlatform.
+// CategoryOther is returned for unknown instructions.
+func (c *Capdu) InstructionCategory() InsCategory {
+	categories := interindustryCategories
+	if isProprietaryClass(c.Cla) {
+		categories = proprietaryCategories
+	}
+
+	if category, ok := categories[c.Ins]; ok {
+		return category
+	}
+
+	return CategoryOther
+}
+
+// managementInstructions contains the GlobalPlatform instructions that change the content or life cycle of a card.
+var managementInstructions = map[byte]bool{
+	0xD8: true, // PUT KEY
+	0xE2: true, // STORE DATA
+	0xE4: true, // DELETE
+	0xE6: true, // INSTALL
+	0xE8: true, // LOAD
+	0xF0: true, // SET STATUS
+}
+
+// IsManagementCommand returns true if the Capdu is a GlobalPlatform card management command, i.e. INSTALL ('E6'),
+// LOAD ('E8'), DELETE ('E4'), PUT KEY ('D8'), SET STATUS ('F0') or STORE DATA ('E2') with a proprietary class,
+// else false. These commands usually require elevated privileges.
+func (c *Capdu) IsManagementCommand() bool {
+	return isProprietaryClass(c.Cla) && managementInstructions[c.Ins]
+}
+
+// InstructionAllowed returns true if the instruction of the Capdu is contained in allowed, else false.
+func (c *Capdu) InstructionAllowed(allowed map[byte]bool) bool {
+	return allowed[c.Ins]
+}
+
+// interindustryIdempotentInstructions contains the instructions of ISO 7816-4 that do not change data on the card.
+var interindustryIdempotentInstructions = map[byte]bool{
+	0x84: true, // GET CHALLENGE
+	0xA4: true, // SELECT
+	0xB0: true, // READ BINARY
+	0xB1: true, // READ BINARY
+	0xB2: true, // READ RECORD(S)
+	0xB3: true, // READ RECORD(S)
+	0xCA: true, // GET DATA
+	0xCB: true, // GET DATA
+}
+
+// proprietaryIdempotentInstructions contains the instructions of GlobalPlatform that do not change data on the card.
+var proprietaryIdempotentInstructions = map[byte]bool{
+	0xCA: true, // GET DATA
+	0xCB: true, // GET DATA
+	0xF2: true, // GET STATUS
+}
+
+// IsIdempotent returns true if the Capdu can be safely transmitted again, e.g. after a transmission error, because
+// its instruction does not change data on the card. This is the case for SELECT, READ BINARY, READ RECORD(S),
+// GET DATA and GET CHALLENGE with an interindustry class and GET DATA and GET STATUS with a proprietary class.
+// Unknown instructions are conservatively considered not idempotent.
+func (c *Capdu) IsIdempotent() bool {
+	if isProprietaryClass(c.Cla) {
+		return proprietaryIdempotentInstructions[c.Ins]
+	}
+
+	return interindustryIdempotentInstructions[c.Ins]
+}
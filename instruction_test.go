@@ -0,0 +1,145 @@
+package apdu
+
+import (
+	"testing"
+)
+
+func TestCapdu_InstructionCategory(t *testing.T) {
+	tests := []struct {
+		name  string
+		capdu *Capdu
+		want  InsCategory
+	}{
+		{name: "SELECT", capdu: &Capdu{Cla: 0x00, Ins: 0xA4}, want: CategoryRead},
+		{name: "READ BINARY", capdu: &Capdu{Cla: 0x00, Ins: 0xB0}, want: CategoryRead},
+		{name: "UPDATE RECORD", capdu: &Capdu{Cla: 0x00, Ins: 0xDC}, want: CategoryWrite},
+		{name: "APPEND RECORD", capdu: &Capdu{Cla: 0x00, Ins: 0xE2}, want: CategoryWrite},
+		{name: "VERIFY", capdu: &Capdu{Cla: 0x00, Ins: 0x20}, want: CategorySecurity},
+		{name: "CREATE FILE", capdu: &Capdu{Cla: 0x00, Ins: 0xE0}, want: CategoryAdmin},
+		{name: "GP GET STATUS", capdu: &Capdu{Cla: 0x80, Ins: 0xF2}, want: CategoryRead},
+		{name: "GP STORE DATA", capdu: &Capdu{Cla: 0x80, Ins: 0xE2}, want: CategoryWrite},
+		{name: "GP INITIALIZE UPDATE", capdu: &Capdu{Cla: 0x80, Ins: 0x50}, want: CategorySecurity},
+		{name: "GP INSTALL with SM", capdu: &Capdu{Cla: 0x84, Ins: 0xE6}, want: CategoryAdmin},
+		{name: "unknown interindustry", capdu: &Capdu{Cla: 0x00, Ins: 0x70}, want: CategoryOther},
+		{name: "unknown proprietary", capdu: &Capdu{Cla: 0x80, Ins: 0xB0}, want: CategoryOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.capdu.InstructionCategory(); got != tt.want {
+				t.Errorf("InstructionCategory() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInsCategory_String(t *testing.T) {
+	tests := []struct {
+		category InsCategory
+		want     string
+	}{
+		{category: CategoryOther, want: "other"},
+		{category: CategoryRead, want: "read"},
+		{category: CategoryWrite, want: "write"},
+		{category: CategorySecurity, want: "security"},
+		{category: CategoryAdmin, want: "admin"},
+		{category: InsCategory(99), want: "other"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.category.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapdu_IsManagementCommand(t *testing.T) {
+	tests := []struct {
+		name  string
+		capdu *Capdu
+		want  bool
+	}{
+		{name: "INSTALL", capdu: &Capdu{Cla: 0x80, Ins: 0xE6}, want: true},
+		{name: "LOAD", capdu: &Capdu{Cla: 0x80, Ins: 0xE8}, want: true},
+		{name: "DELETE", capdu: &Capdu{Cla: 0x80, Ins: 0xE4}, want: true},
+		{name: "PUT KEY with SM", capdu: &Capdu{Cla: 0x84, Ins: 0xD8}, want: true},
+		{name: "SET STATUS on channel 4", capdu: &Capdu{Cla: 0xC0, Ins: 0xF0}, want: true},
+		{name: "STORE DATA", capdu: &Capdu{Cla: 0x80, Ins: 0xE2}, want: true},
+		{name: "GET STATUS", capdu: &Capdu{Cla: 0x80, Ins: 0xF2}, want: false},
+		{name: "READ BINARY", capdu: &Capdu{Cla: 0x00, Ins: 0xB0}, want: false},
+		{name: "SELECT", capdu: &Capdu{Cla: 0x00, Ins: 0xA4}, want: false},
+		{name: "interindustry APPEND RECORD", capdu: &Capdu{Cla: 0x00, Ins: 0xE2}, want: false},
+		{name: "interindustry DELETE FILE", capdu: &Capdu{Cla: 0x00, Ins: 0xE4}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.capdu.IsManagementCommand(); got != tt.want {
+				t.Errorf("IsManagementCommand() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapdu_InstructionAllowed(t *testing.T) {
+	allowed := map[byte]bool{0xA4: true, 0xB0: true, 0xC0: true, 0xCA: false}
+
+	tests := []struct {
+		name  string
+		capdu *Capdu
+		want  bool
+	}{
+		{name: "SELECT", capdu: &Capdu{Cla: 0x00, Ins: 0xA4}, want: true},
+		{name: "READ BINARY", capdu: &Capdu{Cla: 0x00, Ins: 0xB0}, want: true},
+		{name: "explicitly disallowed GET DATA", capdu: &Capdu{Cla: 0x00, Ins: 0xCA}, want: false},
+		{name: "UPDATE BINARY", capdu: &Capdu{Cla: 0x00, Ins: 0xD6}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.capdu.InstructionAllowed(allowed); got != tt.want {
+				t.Errorf("InstructionAllowed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapdu_InstructionAllowed_NilSet(t *testing.T) {
+	if (&Capdu{Cla: 0x00, Ins: 0xA4}).InstructionAllowed(nil) {
+		t.Errorf("InstructionAllowed() = true for nil set, want false")
+	}
+}
+
+func TestCapdu_IsIdempotent(t *testing.T) {
+	tests := []struct {
+		name  string
+		capdu *Capdu
+		want  bool
+	}{
+		{name: "SELECT", capdu: &Capdu{Cla: 0x00, Ins: 0xA4}, want: true},
+		{name: "READ BINARY", capdu: &Capdu{Cla: 0x00, Ins: 0xB0}, want: true},
+		{name: "READ RECORD on channel 1", capdu: &Capdu{Cla: 0x01, Ins: 0xB2}, want: true},
+		{name: "GET DATA", capdu: &Capdu{Cla: 0x00, Ins: 0xCA}, want: true},
+		{name: "GET CHALLENGE", capdu: &Capdu{Cla: 0x00, Ins: 0x84}, want: true},
+		{name: "GP GET STATUS", capdu: &Capdu{Cla: 0x80, Ins: 0xF2}, want: true},
+		{name: "GP GET DATA with SM", capdu: &Capdu{Cla: 0x84, Ins: 0xCA}, want: true},
+		{name: "UPDATE BINARY", capdu: &Capdu{Cla: 0x00, Ins: 0xD6}, want: false},
+		{name: "WRITE RECORD", capdu: &Capdu{Cla: 0x00, Ins: 0xD2}, want: false},
+		{name: "VERIFY", capdu: &Capdu{Cla: 0x00, Ins: 0x20}, want: false},
+		{name: "GP PUT KEY", capdu: &Capdu{Cla: 0x80, Ins: 0xD8}, want: false},
+		{name: "GP INSTALL", capdu: &Capdu{Cla: 0x80, Ins: 0xE6}, want: false},
+		{name: "GP STORE DATA", capdu: &Capdu{Cla: 0x80, Ins: 0xE2}, want: false},
+		{name: "unknown interindustry", capdu: &Capdu{Cla: 0x00, Ins: 0x70}, want: false},
+		{name: "unknown proprietary", capdu: &Capdu{Cla: 0x80, Ins: 0xB0}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.capdu.IsIdempotent(); got != tt.want {
+				t.Errorf("IsIdempotent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
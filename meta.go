@@ -0,0 +1,18 @@
+package apdu
+
+// SetMeta sets the metadata value for key in Meta and creates Meta if it is nil. Metadata does not affect the
+// encoding or the comparison of the Capdu and is copied together with the Capdu by e.g. WithNe.
+func (c *Capdu) SetMeta(key string, value interface{}) {
+	if c.Meta == nil {
+		c.Meta = make(map[string]interface{})
+	}
+
+	c.Meta[key] = value
+}
+
+// GetMeta returns the metadata value for key from Meta and true, or nil and false if key is not present.
+func (c *Capdu) GetMeta(key string) (interface{}, bool) {
+	v, ok := c.Meta[key]
+
+	return v, ok
+}
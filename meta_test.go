@@ -0,0 +1,84 @@
+package apdu
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCapdu_SetMeta(t *testing.T) {
+	c := &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00}
+
+	c.SetMeta("correlationID", "abc")
+	c.SetMeta("attempt", 2)
+
+	tests := []struct {
+		key    string
+		want   interface{}
+		wantOk bool
+	}{
+		{key: "correlationID", want: "abc", wantOk: true},
+		{key: "attempt", want: 2, wantOk: true},
+		{key: "absent", want: nil, wantOk: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got, gotOk := c.GetMeta(tt.key)
+			if gotOk != tt.wantOk {
+				t.Errorf("GetMeta() gotOk = %v, want %v", gotOk, tt.wantOk)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetMeta() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapdu_GetMeta_NilMeta(t *testing.T) {
+	c := &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00}
+
+	if got, ok := c.GetMeta("key"); ok || got != nil {
+		t.Errorf("GetMeta() = %v, %v, want nil, false", got, ok)
+	}
+}
+
+func TestCapdu_Meta_IgnoredByBytesAndEqual(t *testing.T) {
+	c := &Capdu{Cla: 0x80, Ins: 0xF2, P1: 0xE0, P2: 0x02, Data: []byte{0x4F, 0x00}, Ne: 256}
+	withMeta := &Capdu{Cla: 0x80, Ins: 0xF2, P1: 0xE0, P2: 0x02, Data: []byte{0x4F, 0x00}, Ne: 256}
+	withMeta.SetMeta("correlationID", "abc")
+
+	b, err := c.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes() error = %v", err)
+	}
+
+	bWithMeta, err := withMeta.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(b, bWithMeta) {
+		t.Errorf("Bytes() with Meta = %X, want %X", bWithMeta, b)
+	}
+
+	if !c.Equal(withMeta) || !withMeta.Equal(c) {
+		t.Errorf("Equal() = false for Capdus that differ only in Meta, want true")
+	}
+}
+
+func TestCapdu_Meta_CopiedByWithNe(t *testing.T) {
+	c := &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00}
+	c.SetMeta("correlationID", "abc")
+
+	cp := c.WithNe(256)
+	cp.SetMeta("correlationID", "def")
+
+	if got, _ := c.GetMeta("correlationID"); got != "abc" {
+		t.Errorf("GetMeta() of original = %v, modification of the copy must not affect the original", got)
+	}
+
+	if got, _ := cp.GetMeta("correlationID"); got != "def" {
+		t.Errorf("GetMeta() of copy = %v, want def", got)
+	}
+}
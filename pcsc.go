@@ -0,0 +1,14 @@
+package apdu
+
+// PCSCBytes returns the buffer that is passed as send buffer to SCardTransmit of PC/SC. It is identical to the
+// result of Bytes, since PC/SC expects the command in its ISO 7816-4 encoding including the Le field.
+// Use ExpectedResponseBufferLen to size the receive buffer.
+func (c *Capdu) PCSCBytes() ([]byte, error) {
+	return c.Bytes()
+}
+
+// ExpectedResponseBufferLen returns the length of the receive buffer that is passed to SCardTransmit of PC/SC, which
+// is Ne plus the length of the trailer (SW1 SW2), see MaxResponseFrameSize.
+func (c *Capdu) ExpectedResponseBufferLen() int {
+	return c.MaxResponseFrameSize()
+}
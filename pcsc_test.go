@@ -0,0 +1,71 @@
+package apdu
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCapdu_PCSCBytes(t *testing.T) {
+	tests := []struct {
+		name    string
+		capdu   *Capdu
+		want    []byte
+		wantErr bool
+	}{
+		{
+			name:    "Case 2",
+			capdu:   &Capdu{Cla: 0x80, Ins: 0xCA, P1: 0x00, P2: 0x66, Ne: 256},
+			want:    []byte{0x80, 0xCA, 0x00, 0x66, 0x00},
+			wantErr: false,
+		},
+		{
+			name:    "Case 4",
+			capdu:   &Capdu{Cla: 0x80, Ins: 0xF2, P1: 0xE0, P2: 0x02, Data: []byte{0x4F, 0x00}, Ne: 256},
+			want:    []byte{0x80, 0xF2, 0xE0, 0x02, 0x02, 0x4F, 0x00, 0x00},
+			wantErr: false,
+		},
+		{
+			name:    "error: invalid Ne",
+			capdu:   &Capdu{Cla: 0x00, Ins: 0xB0, Ne: 65537},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.capdu.PCSCBytes()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PCSCBytes() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PCSCBytes() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapdu_ExpectedResponseBufferLen(t *testing.T) {
+	tests := []struct {
+		name  string
+		capdu *Capdu
+		want  int
+	}{
+		{name: "Case 1", capdu: &Capdu{Cla: 0x00, Ins: 0x44}, want: 2},
+		{name: "Case 3", capdu: &Capdu{Cla: 0x00, Ins: 0xD6, Data: []byte{0x01}}, want: 2},
+		{name: "Case 2", capdu: &Capdu{Cla: 0x00, Ins: 0xB0, Ne: 16}, want: 18},
+		{name: "Case 4 standard max", capdu: &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, Data: []byte{0x01}, Ne: 256}, want: 258},
+		{name: "Case 2 extended max", capdu: &Capdu{Cla: 0x00, Ins: 0xB0, Ne: 65536}, want: 65538},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.capdu.ExpectedResponseBufferLen(); got != tt.want {
+				t.Errorf("ExpectedResponseBufferLen() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
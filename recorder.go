@@ -0,0 +1,87 @@
+package apdu
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Recorder records exchanges of Command and Response APDUs with the time they were recorded.
+// The zero value is ready to use and a Recorder is safe for concurrent use.
+type Recorder struct {
+	mu        sync.Mutex
+	exchanges []recordedExchange
+	now       func() time.Time
+}
+
+type recordedExchange struct {
+	t time.Time
+	c Capdu
+	r Rapdu
+}
+
+// Record records the exchange of c and r with the current time. Copies of c and r are recorded, so they can be
+// modified after Record returns. An error is returned if c or r is nil.
+func (rec *Recorder) Record(c *Capdu, r *Rapdu) error {
+	if c == nil || r == nil {
+		return errors.Errorf("%s: record exchange - Capdu and Rapdu must not be nil", packageTag)
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	now := time.Now
+	if rec.now != nil {
+		now = rec.now
+	}
+
+	e := recordedExchange{t: now(), c: *c, r: *r}
+	e.c.Data = append([]byte(nil), c.Data...)
+	e.r.Data = append([]byte(nil), r.Data...)
+
+	rec.exchanges = append(rec.exchanges, e)
+
+	return nil
+}
+
+// Export writes the recorded exchanges in the order they were recorded to w. Each exchange is written as two lines,
+// the first one for the Capdu and the second one for the Rapdu, each consisting of the timestamp in RFC 3339 format
+// with nanoseconds, the direction and the hex encoded APDU:
+//
+//	2006-01-02T15:04:05.999999999Z07:00 => 00A4040000
+//	2006-01-02T15:04:05.999999999Z07:00 <= 9000
+//
+// The command data is never redacted (see RedactSensitive), so the exported exchanges can always be parsed and replayed.
+func (rec *Recorder) Export(w io.Writer) error {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	for i := range rec.exchanges {
+		e := &rec.exchanges[i]
+
+		b, err := e.c.Bytes()
+		if err != nil {
+			return errors.Wrapf(err, "%s: export exchange %d", packageTag, i)
+		}
+
+		c := strings.ToUpper(hex.EncodeToString(b))
+
+		r, err := e.r.String()
+		if err != nil {
+			return errors.Wrapf(err, "%s: export exchange %d", packageTag, i)
+		}
+
+		ts := e.t.Format(time.RFC3339Nano)
+
+		if _, err = fmt.Fprintf(w, "%s => %s\n%s <= %s\n", ts, c, ts, r); err != nil {
+			return errors.Wrapf(err, "%s: export exchange %d", packageTag, i)
+		}
+	}
+
+	return nil
+}
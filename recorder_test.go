@@ -0,0 +1,268 @@
+package apdu
+
+import (
+	"bufio"
+	"bytes"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type exchange struct {
+	c *Capdu
+	r *Rapdu
+}
+
+func TestRecorder_Record(t *testing.T) {
+	tests := []struct {
+		name     string
+		exchange exchange
+		wantErr  bool
+	}{
+		{
+			name:     "Capdu and Rapdu",
+			exchange: exchange{c: &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 1}, r: &Rapdu{Data: []byte{0x01}, SW1: 0x90, SW2: 0x00}},
+			wantErr:  false,
+		},
+		{
+			name:     "error: nil Capdu",
+			exchange: exchange{c: nil, r: &Rapdu{SW1: 0x90, SW2: 0x00}},
+			wantErr:  true,
+		},
+		{
+			name:     "error: nil Rapdu",
+			exchange: exchange{c: &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 1}, r: nil},
+			wantErr:  true,
+		},
+		{
+			name:     "error: nil Capdu and Rapdu",
+			exchange: exchange{},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &Recorder{}
+
+			err := rec.Record(tt.exchange.c, tt.exchange.r)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Record() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			wantLen := 1
+			if tt.wantErr {
+				wantLen = 0
+			}
+
+			if len(rec.exchanges) != wantLen {
+				t.Errorf("Record() recorded %d exchanges, want %d", len(rec.exchanges), wantLen)
+			}
+		})
+	}
+}
+
+func TestRecorder_Export(t *testing.T) {
+	ts := time.Date(2021, 3, 4, 10, 20, 30, 123, time.UTC)
+
+	tests := []struct {
+		name      string
+		exchanges []exchange
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:      "empty",
+			exchanges: nil,
+			want:      "",
+			wantErr:   false,
+		},
+		{
+			name: "two exchanges",
+			exchanges: []exchange{
+				{c: &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0xA0, 0x00}, Ne: 256}, r: &Rapdu{Data: []byte{0x6F, 0x00}, SW1: 0x90, SW2: 0x00}},
+				{c: &Capdu{Cla: 0x80, Ins: 0xCA, P1: 0x9F, P2: 0x7F, Ne: 256}, r: &Rapdu{SW1: 0x6A, SW2: 0x88}},
+			},
+			want: "2021-03-04T10:20:30.000000123Z => 00A4040002A00000\n" +
+				"2021-03-04T10:20:30.000000123Z <= 6F009000\n" +
+				"2021-03-04T10:20:30.000000123Z => 80CA9F7F00\n" +
+				"2021-03-04T10:20:30.000000123Z <= 6A88\n",
+			wantErr: false,
+		},
+		{
+			name: "error: invalid Capdu",
+			exchanges: []exchange{
+				{c: &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Ne: 65537}, r: &Rapdu{SW1: 0x90, SW2: 0x00}},
+			},
+			want:    "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &Recorder{now: func() time.Time { return ts }}
+			for _, e := range tt.exchanges {
+				if err := rec.Record(e.c, e.r); err != nil {
+					t.Fatalf("Record() error = %v", err)
+				}
+			}
+
+			buf := &bytes.Buffer{}
+
+			err := rec.Export(buf)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Export() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+			if !tt.wantErr && buf.String() != tt.want {
+				t.Errorf("Export() got = %v, want %v", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRecorder_RoundTrip(t *testing.T) {
+	capdus := []*Capdu{
+		{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0xA0, 0x00, 0x00, 0x01, 0x51}, Ne: 256},
+		{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 65536},
+	}
+	rapdus := []*Rapdu{
+		{Data: []byte{0x6F, 0x00}, SW1: 0x90, SW2: 0x00},
+		{SW1: 0x6B, SW2: 0x00},
+	}
+
+	rec := &Recorder{}
+	for i := range capdus {
+		if err := rec.Record(capdus[i], rapdus[i]); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := rec.Export(buf); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	gotCapdus, gotRapdus := parseExport(t, buf)
+
+	if !reflect.DeepEqual(gotCapdus, capdus) {
+		t.Errorf("round trip Capdus got = %v, want %v", gotCapdus, capdus)
+	}
+
+	if !reflect.DeepEqual(gotRapdus, rapdus) {
+		t.Errorf("round trip Rapdus got = %v, want %v", gotRapdus, rapdus)
+	}
+}
+
+// parseExport parses the lines written by Recorder.Export and returns the Capdus and Rapdus in order.
+func parseExport(t *testing.T, buf *bytes.Buffer) ([]*Capdu, []*Rapdu) {
+	t.Helper()
+
+	var (
+		gotCapdus []*Capdu
+		gotRapdus []*Rapdu
+	)
+
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			t.Fatalf("Export() line %q does not consist of 3 fields", scanner.Text())
+		}
+
+		if _, err := time.Parse(time.RFC3339Nano, fields[0]); err != nil {
+			t.Fatalf("Export() invalid timestamp: %v", err)
+		}
+
+		switch fields[1] {
+		case "=>":
+			c, err := ParseCapduHexString(fields[2])
+			if err != nil {
+				t.Fatalf("ParseCapduHexString() error = %v", err)
+			}
+
+			gotCapdus = append(gotCapdus, c)
+		case "<=":
+			r, err := ParseRapduHexString(fields[2])
+			if err != nil {
+				t.Fatalf("ParseRapduHexString() error = %v", err)
+			}
+
+			gotRapdus = append(gotRapdus, r)
+		default:
+			t.Fatalf("Export() invalid direction %q", fields[1])
+		}
+	}
+
+	return gotCapdus, gotRapdus
+}
+
+func TestRecorder_Export_RedactSensitive(t *testing.T) {
+	defer func(redact bool) { RedactSensitive = redact }(RedactSensitive)
+
+	RedactSensitive = true
+
+	capdus := []*Capdu{
+		{Cla: 0x00, Ins: 0x20, P1: 0x00, P2: 0x80, Data: []byte{0x31, 0x32, 0x33, 0x34, 0xFF, 0xFF, 0xFF, 0xFF}},
+		{Cla: 0x84, Ins: 0xD8, P1: 0x01, P2: 0x81, Data: []byte{0x01, 0x80, 0x10}},
+	}
+	rapdus := []*Rapdu{
+		{SW1: 0x90, SW2: 0x00},
+		{Data: []byte{0x01}, SW1: 0x90, SW2: 0x00},
+	}
+
+	rec := &Recorder{}
+	for i := range capdus {
+		if err := rec.Record(capdus[i], rapdus[i]); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := rec.Export(buf); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	gotCapdus, gotRapdus := parseExport(t, buf)
+
+	if !reflect.DeepEqual(gotCapdus, capdus) {
+		t.Errorf("round trip Capdus got = %v, want %v", gotCapdus, capdus)
+	}
+
+	if !reflect.DeepEqual(gotRapdus, rapdus) {
+		t.Errorf("round trip Rapdus got = %v, want %v", gotRapdus, rapdus)
+	}
+}
+
+func TestRecorder_Concurrent(t *testing.T) {
+	rec := &Recorder{}
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			if err := rec.Record(&Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 1}, &Rapdu{Data: []byte{0x01}, SW1: 0x90, SW2: 0x00}); err != nil {
+				t.Errorf("Record() error = %v", err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	buf := &bytes.Buffer{}
+	if err := rec.Export(buf); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	if got := strings.Count(buf.String(), "\n"); got != 100 {
+		t.Errorf("Export() got %d lines, want %d", got, 100)
+	}
+}
@@ -0,0 +1,55 @@
+package apdu
+
+import (
+	"encoding/hex"
+	"strings"
+)
+
+// RedactSensitive controls whether Capdu.String and the slog representation of a Capdu replace the command data of
+// sensitive commands with 'XX' for each byte, which prevents PINs and keys from leaking into logs. It is false by
+// default and should be set once before Capdus are formatted, since it is not safe for concurrent modification.
+//
+// The following commands are considered sensitive:
+//   - VERIFY ('20' and '21'), CHANGE REFERENCE DATA ('24') and RESET RETRY COUNTER ('2C') of interindustry classes,
+//     which transport PINs
+//   - PUT KEY ('D8') of proprietary classes (GlobalPlatform), which transports keys
+var RedactSensitive = false
+
+// redactedData is the placeholder for each redacted byte of command data.
+const redactedData = "XX"
+
+// sensitiveInterindustryInstructions contains the instructions of interindustry classes whose data is redacted.
+var sensitiveInterindustryInstructions = map[byte]bool{
+	0x20: true, // VERIFY
+	0x21: true, // VERIFY
+	0x24: true, // CHANGE REFERENCE DATA
+	0x2C: true, // RESET RETRY COUNTER
+}
+
+// sensitiveProprietaryInstructions contains the instructions of proprietary classes whose data is redacted.
+var sensitiveProprietaryInstructions = map[byte]bool{
+	0xD8: true, // PUT KEY
+}
+
+// isSensitive returns true if the command data of the Capdu contains secrets according to RedactSensitive, else false.
+func (c *Capdu) isSensitive() bool {
+	if isProprietaryClass(c.Cla) {
+		return sensitiveProprietaryInstructions[c.Ins]
+	}
+
+	return sensitiveInterindustryInstructions[c.Ins]
+}
+
+// redactData returns true if RedactSensitive is set and the Capdu contains sensitive data, else false.
+func (c *Capdu) redactData() bool {
+	return RedactSensitive && len(c.Data) > 0 && c.isSensitive()
+}
+
+// dataString returns the hex encoded command data, which is redacted if redactData returns true.
+func (c *Capdu) dataString() string {
+	if c.redactData() {
+		return strings.Repeat(redactedData, len(c.Data))
+	}
+
+	return strings.ToUpper(hex.EncodeToString(c.Data))
+}
@@ -0,0 +1,80 @@
+package apdu
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCapdu_String_RedactSensitive(t *testing.T) {
+	tests := []struct {
+		name            string
+		capdu           *Capdu
+		wantRedacted    string
+		wantNotRedacted string
+	}{
+		{
+			name:            "VERIFY",
+			capdu:           &Capdu{Cla: 0x00, Ins: 0x20, P1: 0x00, P2: 0x80, Data: []byte{0x24, 0x12, 0x34, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}},
+			wantRedacted:    "0020008008XXXXXXXXXXXXXXXX",
+			wantNotRedacted: "0020008008241234FFFFFFFFFF",
+		},
+		{
+			name:            "CHANGE REFERENCE DATA with Le",
+			capdu:           &Capdu{Cla: 0x00, Ins: 0x24, P1: 0x00, P2: 0x80, Data: []byte{0x12, 0x34}, Ne: 256},
+			wantRedacted:    "0024008002XXXX00",
+			wantNotRedacted: "0024008002123400",
+		},
+		{
+			name:            "PUT KEY extended",
+			capdu:           &Capdu{Cla: 0x84, Ins: 0xD8, P1: 0x01, P2: 0x81, Data: make([]byte, 256)},
+			wantRedacted:    "84D80181000100" + strings.Repeat("XX", 256),
+			wantNotRedacted: "84D80181000100" + strings.Repeat("00", 256),
+		},
+		{
+			name:            "VERIFY without data",
+			capdu:           &Capdu{Cla: 0x00, Ins: 0x20, P1: 0x00, P2: 0x80},
+			wantRedacted:    "00200080",
+			wantNotRedacted: "00200080",
+		},
+		{
+			name:            "SELECT is not sensitive",
+			capdu:           &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0xA0, 0x00}, Ne: 256},
+			wantRedacted:    "00A4040002A00000",
+			wantNotRedacted: "00A4040002A00000",
+		},
+		{
+			name:            "proprietary 0x20 is not sensitive",
+			capdu:           &Capdu{Cla: 0x80, Ins: 0x20, P1: 0x00, P2: 0x00, Data: []byte{0x01}},
+			wantRedacted:    "802000000101",
+			wantNotRedacted: "802000000101",
+		},
+	}
+
+	defer func(redact bool) { RedactSensitive = redact }(RedactSensitive)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			RedactSensitive = true
+
+			got, err := tt.capdu.String()
+			if err != nil {
+				t.Fatalf("String() error = %v", err)
+			}
+
+			if got != tt.wantRedacted {
+				t.Errorf("String() with RedactSensitive got = %v, want %v", got, tt.wantRedacted)
+			}
+
+			RedactSensitive = false
+
+			got, err = tt.capdu.String()
+			if err != nil {
+				t.Fatalf("String() error = %v", err)
+			}
+
+			if got != tt.wantNotRedacted {
+				t.Errorf("String() without RedactSensitive got = %v, want %v", got, tt.wantNotRedacted)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package apdu
+
+// lenSCP03Block is the block length of AES, which is used by GlobalPlatform SCP03 to encrypt command data.
+const lenSCP03Block int = 16
+
+// SecureMessagingOverhead estimates the number of bytes a secure channel adds to the encoding of c, which allows to
+// check whether the wrapped command still fits into the buffer of a reader. No cryptographic operations are
+// performed.
+// The estimation assumes that the command data is padded to the AES block length of 16 byte according to
+// ISO/IEC 9797-1 padding method 2 (a mandatory '80' followed by zero bytes) if pad is true and the command contains
+// data, and that a MAC of macLen byte is appended to the data. A growth of the Lc (and Le) field, e.g. if the wrapped
+// data exceeds 255 byte and requires the extended length format, is included. A negative macLen is treated as zero.
+func SecureMessagingOverhead(c *Capdu, macLen int, pad bool) int {
+	if macLen < 0 {
+		macLen = 0
+	}
+
+	dataLen := len(c.Data)
+	wrappedLen := dataLen
+
+	if pad && dataLen > 0 {
+		wrappedLen = (dataLen/lenSCP03Block + 1) * lenSCP03Block
+	}
+
+	wrappedLen += macLen
+
+	return lenEncoded(wrappedLen, c.MaxResponseBytes()) - lenEncoded(dataLen, c.MaxResponseBytes())
+}
+
+// lenEncoded returns the length of the encoding of a Capdu with dataLen byte of data and the given ne.
+func lenEncoded(dataLen, ne int) int {
+	extended := dataLen > MaxLenCommandDataStandard || ne > MaxLenResponseDataStandard
+	n := LenHeader + dataLen
+
+	if dataLen > 0 {
+		if extended {
+			n += LenLCExtended
+		} else {
+			n += LenLCStandard
+		}
+	}
+
+	if ne > 0 {
+		switch {
+		case !extended:
+			n++
+		case dataLen > 0:
+			n += 2
+		default:
+			n += LenLCExtended
+		}
+	}
+
+	return n
+}
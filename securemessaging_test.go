@@ -0,0 +1,93 @@
+package apdu
+
+import "testing"
+
+func TestSecureMessagingOverhead(t *testing.T) {
+	type args struct {
+		c      *Capdu
+		macLen int
+		pad    bool
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want int
+	}{
+		{
+			name: "Case 1 with MAC",
+			args: args{c: &Capdu{Cla: 0x84, Ins: 0xF2}, macLen: 8, pad: true},
+			want: 9, // Lc and MAC
+		},
+		{
+			name: "Case 3 with MAC only",
+			args: args{c: &Capdu{Cla: 0x84, Ins: 0xE2, Data: make([]byte, 10)}, macLen: 8, pad: false},
+			want: 8,
+		},
+		{
+			name: "Case 3 padded below block boundary",
+			args: args{c: &Capdu{Cla: 0x84, Ins: 0xE2, Data: make([]byte, 15)}, macLen: 8, pad: true},
+			want: 1 + 8,
+		},
+		{
+			name: "Case 3 padded at block boundary",
+			args: args{c: &Capdu{Cla: 0x84, Ins: 0xE2, Data: make([]byte, 16)}, macLen: 8, pad: true},
+			want: 16 + 8,
+		},
+		{
+			name: "Case 3 padded above block boundary",
+			args: args{c: &Capdu{Cla: 0x84, Ins: 0xE2, Data: make([]byte, 17)}, macLen: 8, pad: true},
+			want: 15 + 8,
+		},
+		{
+			name: "Case 4 padded",
+			args: args{c: &Capdu{Cla: 0x84, Ins: 0xE2, Data: make([]byte, 32), Ne: 256}, macLen: 16, pad: true},
+			want: 16 + 16,
+		},
+		{
+			name: "Case 4 wrapped data requires extended length",
+			args: args{c: &Capdu{Cla: 0x84, Ins: 0xE2, Data: make([]byte, 240), Ne: 256}, macLen: 8, pad: true},
+			want: 16 + 8 + 2 + 1, // padding, MAC, extended Lc and extended Le
+		},
+		{
+			name: "Case 1 without MAC",
+			args: args{c: &Capdu{Cla: 0x80, Ins: 0xF2}, macLen: 0, pad: true},
+			want: 0,
+		},
+		{
+			name: "negative MAC length",
+			args: args{c: &Capdu{Cla: 0x84, Ins: 0xE2, Data: make([]byte, 10)}, macLen: -1, pad: false},
+			want: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SecureMessagingOverhead(tt.args.c, tt.args.macLen, tt.args.pad); got != tt.want {
+				t.Errorf("SecureMessagingOverhead() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_lenEncoded(t *testing.T) {
+	for _, c := range []*Capdu{
+		{Cla: 0x00, Ins: 0x70},
+		{Cla: 0x00, Ins: 0xB0, Ne: 256},
+		{Cla: 0x00, Ins: 0xB0, Ne: 65536},
+		{Cla: 0x00, Ins: 0xD6, Data: make([]byte, 255)},
+		{Cla: 0x00, Ins: 0xD6, Data: make([]byte, 256)},
+		{Cla: 0x00, Ins: 0xA4, Data: make([]byte, 255), Ne: 256},
+		{Cla: 0x00, Ins: 0xA4, Data: make([]byte, 10), Ne: 257},
+		{Cla: 0x00, Ins: 0xA4, Data: make([]byte, 65535), Ne: 65536},
+	} {
+		b, err := c.Bytes()
+		if err != nil {
+			t.Fatalf("Bytes() error = %v", err)
+		}
+
+		if got := lenEncoded(len(c.Data), c.Ne); got != len(b) {
+			t.Errorf("lenEncoded(%d, %d) = %d, want %d", len(c.Data), c.Ne, got, len(b))
+		}
+	}
+}
@@ -0,0 +1,55 @@
+//go:build go1.21
+// +build go1.21
+
+package apdu
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// LogValue implements slog.LogValuer and returns the Capdu as group of its fields. The encoded Capdu is added
+// as attribute "apdu", or an attribute "error" if the Capdu can't be encoded. The command data of sensitive commands
+// is redacted if RedactSensitive is set.
+func (c *Capdu) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.String("cla", fmt.Sprintf("%02X", c.Cla)),
+		slog.String("ins", fmt.Sprintf("%02X", c.Ins)),
+		slog.String("p1", fmt.Sprintf("%02X", c.P1)),
+		slog.String("p2", fmt.Sprintf("%02X", c.P2)),
+		slog.Int("lc", len(c.Data)),
+		slog.Int("ne", c.Ne),
+		slog.String("data", c.dataString()),
+	}
+
+	if s, err := c.String(); err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	} else {
+		attrs = append(attrs, slog.String("apdu", s))
+	}
+
+	return slog.GroupValue(attrs...)
+}
+
+// LogValue implements slog.LogValuer and returns the RAPDU as group of its data, its status word and the status
+// that is indicated by the status word ("success", "warning", "error" or "unknown").
+func (r *Rapdu) LogValue() slog.Value {
+	status := "unknown"
+
+	switch {
+	case r.IsSuccess():
+		status = "success"
+	case r.IsWarning():
+		status = "warning"
+	case r.IsError():
+		status = "error"
+	}
+
+	return slog.GroupValue(
+		slog.String("data", strings.ToUpper(hex.EncodeToString(r.Data))),
+		slog.String("sw", fmt.Sprintf("%04X", r.sw())),
+		slog.String("status", status),
+	)
+}
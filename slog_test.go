@@ -0,0 +1,114 @@
+//go:build go1.21
+// +build go1.21
+
+package apdu
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+var (
+	_ slog.LogValuer = (*Capdu)(nil)
+	_ slog.LogValuer = (*Rapdu)(nil)
+)
+
+func TestCapdu_LogValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		capdu *Capdu
+		want  string
+	}{
+		{
+			name:  "Case 4",
+			capdu: &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0xA0, 0x00}, Ne: 256},
+			want:  "msg=cmd capdu.cla=00 capdu.ins=A4 capdu.p1=04 capdu.p2=00 capdu.lc=2 capdu.ne=256 capdu.data=A000 capdu.apdu=00A4040002A00000\n",
+		},
+		{
+			name:  "invalid Ne",
+			capdu: &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 65537},
+			want:  "msg=cmd capdu.cla=00 capdu.ins=B0 capdu.p1=00 capdu.p2=00 capdu.lc=0 capdu.ne=65537 capdu.data=\"\" capdu.error=",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			logger := slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{ReplaceAttr: dropTime}))
+
+			logger.Info("cmd", "capdu", tt.capdu)
+
+			if got := strings.TrimPrefix(buf.String(), "level=INFO "); !strings.HasPrefix(got, tt.want) {
+				t.Errorf("LogValue() got = %v, want prefix %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapdu_LogValue_RedactSensitive(t *testing.T) {
+	defer func(redact bool) { RedactSensitive = redact }(RedactSensitive)
+
+	RedactSensitive = true
+
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{ReplaceAttr: dropTime}))
+
+	logger.Info("cmd", "capdu", &Capdu{Cla: 0x00, Ins: 0x20, P1: 0x00, P2: 0x80, Data: []byte{0x12, 0x34}})
+
+	want := "msg=cmd capdu.cla=00 capdu.ins=20 capdu.p1=00 capdu.p2=80 capdu.lc=2 capdu.ne=0 capdu.data=XXXX capdu.apdu=0020008002XXXX\n"
+	if got := strings.TrimPrefix(buf.String(), "level=INFO "); got != want {
+		t.Errorf("LogValue() got = %v, want %v", got, want)
+	}
+}
+
+func TestRapdu_LogValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		rapdu *Rapdu
+		want  string
+	}{
+		{
+			name:  "success",
+			rapdu: &Rapdu{Data: []byte{0x6F, 0x00}, SW1: 0x90, SW2: 0x00},
+			want:  "level=INFO msg=rsp rapdu.data=6F00 rapdu.sw=9000 rapdu.status=success\n",
+		},
+		{
+			name:  "warning",
+			rapdu: &Rapdu{SW1: 0x62, SW2: 0x83},
+			want:  "level=INFO msg=rsp rapdu.data=\"\" rapdu.sw=6283 rapdu.status=warning\n",
+		},
+		{
+			name:  "error",
+			rapdu: &Rapdu{SW1: 0x6A, SW2: 0x82},
+			want:  "level=INFO msg=rsp rapdu.data=\"\" rapdu.sw=6A82 rapdu.status=error\n",
+		},
+		{
+			name:  "unknown",
+			rapdu: &Rapdu{SW1: 0x66, SW2: 0x00},
+			want:  "level=INFO msg=rsp rapdu.data=\"\" rapdu.sw=6600 rapdu.status=unknown\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			logger := slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{ReplaceAttr: dropTime}))
+
+			logger.Info("rsp", "rapdu", tt.rapdu)
+
+			if got := buf.String(); got != tt.want {
+				t.Errorf("LogValue() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func dropTime(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) == 0 && a.Key == slog.TimeKey {
+		return slog.Attr{}
+	}
+
+	return a
+}
@@ -0,0 +1,105 @@
+package apdu
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+type statusWordDescription struct {
+	short string
+	long  string
+}
+
+// statusWords contains descriptions of interindustry status words defined in ISO 7816-4.
+var statusWords = map[uint16]statusWordDescription{
+	0x9000: {short: "SUCCESS", long: "Normal processing, no further qualification"},
+	0x6281: {short: "DATA_CORRUPTED", long: "Warning, part of returned data may be corrupted"},
+	0x6282: {short: "END_OF_FILE", long: "Warning, end of file or record reached before reading Ne bytes"},
+	0x6283: {short: "FILE_DEACTIVATED", long: "Warning, selected file deactivated"},
+	0x6284: {short: "FCI_NOT_FORMATTED", long: "Warning, file control information not formatted according to ISO 7816-4"},
+	0x6300: {short: "MEMORY_CHANGED", long: "Warning, state of non-volatile memory has changed, no information given"},
+	0x6400: {short: "EXECUTION_ERROR", long: "Execution error, state of non-volatile memory unchanged"},
+	0x6581: {short: "MEMORY_FAILURE", long: "Execution error, memory failure"},
+	0x6700: {short: "WRONG_LENGTH", long: "Wrong length, no further indication"},
+	0x6881: {short: "CHANNEL_NOT_SUPPORTED", long: "Function in CLA not supported, logical channel not supported"},
+	0x6882: {short: "SM_NOT_SUPPORTED", long: "Function in CLA not supported, secure messaging not supported"},
+	0x6883: {short: "LAST_COMMAND_EXPECTED", long: "Function in CLA not supported, last command of the chain expected"},
+	0x6884: {short: "CHAINING_NOT_SUPPORTED", long: "Function in CLA not supported, command chaining not supported"},
+	0x6982: {short: "SECURITY_STATUS_NOT_SATISFIED", long: "Command not allowed, security status not satisfied"},
+	0x6983: {short: "AUTH_METHOD_BLOCKED", long: "Command not allowed, authentication method blocked"},
+	0x6984: {short: "REFERENCE_DATA_NOT_USABLE", long: "Command not allowed, reference data not usable"},
+	0x6985: {short: "CONDITIONS_NOT_SATISFIED", long: "Command not allowed, conditions of use not satisfied"},
+	0x6986: {short: "COMMAND_NOT_ALLOWED", long: "Command not allowed, no current EF"},
+	0x6987: {short: "SM_DATA_MISSING", long: "Command not allowed, expected secure messaging data objects missing"},
+	0x6988: {short: "SM_DATA_INCORRECT", long: "Command not allowed, incorrect secure messaging data objects"},
+	0x6A80: {short: "WRONG_DATA", long: "Wrong parameters, incorrect parameters in the command data field"},
+	0x6A81: {short: "FUNCTION_NOT_SUPPORTED", long: "Wrong parameters, function not supported"},
+	0x6A82: {short: "FILE_NOT_FOUND", long: "Wrong parameters, file or application not found"},
+	0x6A83: {short: "RECORD_NOT_FOUND", long: "Wrong parameters, record not found"},
+	0x6A84: {short: "NOT_ENOUGH_MEMORY", long: "Wrong parameters, not enough memory space in the file"},
+	0x6A86: {short: "INCORRECT_P1P2", long: "Wrong parameters, incorrect parameters P1-P2"},
+	0x6A88: {short: "REFERENCED_DATA_NOT_FOUND", long: "Wrong parameters, referenced data or reference data not found"},
+	0x6B00: {short: "WRONG_P1P2", long: "Wrong parameters P1-P2"},
+	0x6D00: {short: "INS_NOT_SUPPORTED", long: "Instruction code not supported or invalid"},
+	0x6E00: {short: "CLA_NOT_SUPPORTED", long: "Class not supported"},
+	0x6F00: {short: "UNKNOWN_ERROR", long: "No precise diagnosis"},
+}
+
+// statusWordRanges contains descriptions of interindustry status words whose SW2 encodes a value, looked up by SW1.
+var statusWordRanges = map[byte]statusWordDescription{
+	0x61: {short: "MORE_DATA", long: "Normal processing, SW2 encodes the number of data bytes still available"},
+	0x6C: {short: "WRONG_LE", long: "Wrong Le field, SW2 encodes the exact number of available data bytes"},
+}
+
+// UnknownStatusWordFormatter is called by Rapdu.Description and all other functions that describe status words if a
+// status word is unknown to LookupStatusWord. It allows to plug in descriptions of proprietary status words. If it is
+// nil (default), unknown status words are described as "Unknown status word XXXX". Like RedactSensitive, it should be
+// set once before status words are described, since it is not safe for concurrent modification.
+var UnknownStatusWordFormatter func(sw uint16) string
+
+// LookupStatusWord returns a short mnemonic (e.g. "WRONG_LENGTH"), a long description of the status word and true
+// if the status word is an interindustry status word defined in ISO 7816-4, else empty strings and false.
+func LookupStatusWord(sw uint16) (short, long string, known bool) {
+	if d, ok := statusWords[sw]; ok {
+		return d.short, d.long, true
+	}
+
+	if d, ok := statusWordRanges[byte(sw>>8)]; ok {
+		return d.short, d.long, true
+	}
+
+	return "", "", false
+}
+
+// Description returns the long description of the status word of the RAPDU according to LookupStatusWord or, if the
+// status word is unknown, the result of UnknownStatusWordFormatter or "Unknown status word XXXX".
+func (r *Rapdu) Description() string {
+	return describeStatusWord(r.sw())
+}
+
+// ExpectSW returns nil if the status word of the RAPDU equals sw, otherwise an error that describes both status words,
+// e.g. "expected SW 9000 (Normal processing, no further qualification), got 6A82 (Wrong parameters, file or
+// application not found)".
+func (r *Rapdu) ExpectSW(sw uint16) error {
+	if r.sw() == sw {
+		return nil
+	}
+
+	return errors.Errorf("%s: expected SW %04X (%s), got %04X (%s)",
+		packageTag, sw, describeStatusWord(sw), r.sw(), describeStatusWord(r.sw()))
+}
+
+// describeStatusWord returns the long description of sw according to LookupStatusWord or, if sw is unknown, the
+// result of UnknownStatusWordFormatter or "Unknown status word XXXX".
+func describeStatusWord(sw uint16) string {
+	if _, long, ok := LookupStatusWord(sw); ok {
+		return long
+	}
+
+	if UnknownStatusWordFormatter != nil {
+		return UnknownStatusWordFormatter(sw)
+	}
+
+	return fmt.Sprintf("Unknown status word %04X", sw)
+}
@@ -0,0 +1,194 @@
+package apdu
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLookupStatusWord(t *testing.T) {
+	tests := []struct {
+		name      string
+		sw        uint16
+		wantShort string
+		wantLong  string
+		wantKnown bool
+	}{
+		{
+			name:      "success",
+			sw:        0x9000,
+			wantShort: "SUCCESS",
+			wantLong:  "Normal processing, no further qualification",
+			wantKnown: true,
+		},
+		{
+			name:      "wrong length",
+			sw:        0x6700,
+			wantShort: "WRONG_LENGTH",
+			wantLong:  "Wrong length, no further indication",
+			wantKnown: true,
+		},
+		{
+			name:      "file not found",
+			sw:        0x6A82,
+			wantShort: "FILE_NOT_FOUND",
+			wantLong:  "Wrong parameters, file or application not found",
+			wantKnown: true,
+		},
+		{
+			name:      "more data",
+			sw:        0x6110,
+			wantShort: "MORE_DATA",
+			wantLong:  "Normal processing, SW2 encodes the number of data bytes still available",
+			wantKnown: true,
+		},
+		{
+			name:      "wrong Le",
+			sw:        0x6C00,
+			wantShort: "WRONG_LE",
+			wantLong:  "Wrong Le field, SW2 encodes the exact number of available data bytes",
+			wantKnown: true,
+		},
+		{
+			name:      "unknown",
+			sw:        0x9F12,
+			wantShort: "",
+			wantLong:  "",
+			wantKnown: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotShort, gotLong, gotKnown := LookupStatusWord(tt.sw)
+			if gotShort != tt.wantShort {
+				t.Errorf("LookupStatusWord() gotShort = %v, want %v", gotShort, tt.wantShort)
+			}
+
+			if gotLong != tt.wantLong {
+				t.Errorf("LookupStatusWord() gotLong = %v, want %v", gotLong, tt.wantLong)
+			}
+
+			if gotKnown != tt.wantKnown {
+				t.Errorf("LookupStatusWord() gotKnown = %v, want %v", gotKnown, tt.wantKnown)
+			}
+		})
+	}
+}
+
+func TestRapdu_Description(t *testing.T) {
+	tests := []struct {
+		name  string
+		rapdu Rapdu
+		want  string
+	}{
+		{
+			name:  "known",
+			rapdu: Rapdu{SW1: 0x69, SW2: 0x82},
+			want:  "Command not allowed, security status not satisfied",
+		},
+		{
+			name:  "unknown",
+			rapdu: Rapdu{SW1: 0x9F, SW2: 0x12},
+			want:  "Unknown status word 9F12",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rapdu.Description(); got != tt.want {
+				t.Errorf("Description() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRapdu_Description_UnknownStatusWordFormatter(t *testing.T) {
+	defer func(f func(uint16) string) { UnknownStatusWordFormatter = f }(UnknownStatusWordFormatter)
+
+	UnknownStatusWordFormatter = func(sw uint16) string {
+		if sw == 0x9F12 {
+			return "Applet specific error"
+		}
+
+		return fmt.Sprintf("Proprietary status word %04X", sw)
+	}
+
+	tests := []struct {
+		name  string
+		rapdu Rapdu
+		want  string
+	}{
+		{
+			name:  "known is not overridden",
+			rapdu: Rapdu{SW1: 0x69, SW2: 0x82},
+			want:  "Command not allowed, security status not satisfied",
+		},
+		{
+			name:  "unknown with custom description",
+			rapdu: Rapdu{SW1: 0x9F, SW2: 0x12},
+			want:  "Applet specific error",
+		},
+		{
+			name:  "unknown with custom fallback",
+			rapdu: Rapdu{SW1: 0x9F, SW2: 0x13},
+			want:  "Proprietary status word 9F13",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rapdu.Description(); got != tt.want {
+				t.Errorf("Description() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRapdu_ExpectSW(t *testing.T) {
+	tests := []struct {
+		name    string
+		rapdu   Rapdu
+		sw      uint16
+		wantErr string
+	}{
+		{
+			name:    "match",
+			rapdu:   Rapdu{Data: []byte{0x01}, SW1: 0x90, SW2: 0x00},
+			sw:      0x9000,
+			wantErr: "",
+		},
+		{
+			name:    "match warning",
+			rapdu:   Rapdu{SW1: 0x62, SW2: 0x83},
+			sw:      0x6283,
+			wantErr: "",
+		},
+		{
+			name:    "mismatch",
+			rapdu:   Rapdu{SW1: 0x6A, SW2: 0x82},
+			sw:      0x9000,
+			wantErr: "skythen/apdu: expected SW 9000 (Normal processing, no further qualification), got 6A82 (Wrong parameters, file or application not found)",
+		},
+		{
+			name:    "mismatch unknown",
+			rapdu:   Rapdu{SW1: 0x9F, SW2: 0x12},
+			sw:      0x6700,
+			wantErr: "skythen/apdu: expected SW 6700 (Wrong length, no further indication), got 9F12 (Unknown status word 9F12)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rapdu.ExpectSW(tt.sw)
+			if (err != nil) != (tt.wantErr != "") {
+				t.Errorf("ExpectSW() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if err != nil && err.Error() != tt.wantErr {
+				t.Errorf("ExpectSW() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
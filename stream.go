@@ -0,0 +1,181 @@
+package apdu
+
+import (
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// LenFramePrefix defines the length of the big-endian length prefix that precedes each frame written by a
+	// CapduEncoder.
+	LenFramePrefix int = 2
+	// MaxLenFrame defines the maximum length of a framed APDU, which is limited by the two byte length prefix.
+	MaxLenFrame int = math.MaxUint16
+)
+
+// CapduEncoder writes Command APDUs to an io.Writer, each prefixed with its length encoded as two byte big-endian value.
+// Since the length prefix can't encode more than MaxLenFrame (65535) byte, extended length Capdus close to MaxLenCapdu,
+// i.e. with more than 65528 byte of data, can't be framed and Encode returns an error for them.
+type CapduEncoder struct {
+	w io.Writer
+}
+
+// NewCapduEncoder returns a CapduEncoder that writes to w.
+func NewCapduEncoder(w io.Writer) *CapduEncoder {
+	return &CapduEncoder{w: w}
+}
+
+// Encode writes the length prefix followed by the byte representation of the Capdu to the underlying io.Writer.
+// The prefix and the command are written with a single call to Write.
+//
+// Errors that occur while encoding the Capdu are returned as they are, errors of the underlying io.Writer
+// are wrapped and can be retrieved with errors.Cause.
+func (e *CapduEncoder) Encode(c *Capdu) error {
+	frame, err := frameCapdu(c)
+	if err != nil {
+		return err
+	}
+
+	if _, err = e.w.Write(frame); err != nil {
+		return errors.Wrapf(err, "%s: write framed Capdu", packageTag)
+	}
+
+	return nil
+}
+
+// WriteFramed writes the length prefix followed by the byte representation of the Capdu to w with a single call to
+// Write, like CapduEncoder.Encode, and returns the number of bytes written including the prefix.
+// Errors that occur while encoding the Capdu are returned as they are, errors of w are wrapped and can be retrieved
+// with errors.Cause. An error is returned if the encoded Capdu exceeds MaxLenFrame (65535) byte.
+func (c *Capdu) WriteFramed(w io.Writer) (int, error) {
+	frame, err := frameCapdu(c)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := w.Write(frame)
+	if err != nil {
+		return n, errors.Wrapf(err, "%s: write framed Capdu", packageTag)
+	}
+
+	return n, nil
+}
+
+// frameCapdu returns the byte representation of the Capdu prefixed with its length or an error if it exceeds
+// MaxLenFrame.
+func frameCapdu(c *Capdu) ([]byte, error) {
+	b, err := c.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b) > MaxLenFrame {
+		return nil, errors.Errorf("%s: len of Capdu %d exceeds maximum frame length of %d", packageTag, len(b), MaxLenFrame)
+	}
+
+	frame := make([]byte, LenFramePrefix, LenFramePrefix+len(b))
+	binary.BigEndian.PutUint16(frame, uint16(len(b)))
+	frame = append(frame, b...)
+
+	return frame, nil
+}
+
+// CapduDecoder reads Command APDUs from an io.Reader, each prefixed with its length encoded as two byte big-endian
+// value, as written by a CapduEncoder.
+type CapduDecoder struct {
+	r io.Reader
+}
+
+// NewCapduDecoder returns a CapduDecoder that reads from r.
+func NewCapduDecoder(r io.Reader) *CapduDecoder {
+	return &CapduDecoder{r: r}
+}
+
+// Decode reads the next length prefix and the framed Capdu from the underlying io.Reader, calls ParseCapdu and returns
+// the Capdu. Short reads are handled by reading until the frame is complete.
+//
+// io.EOF is returned as it is if the io.Reader is exhausted before a new frame starts, so it marks the regular end of
+// the stream. If the stream ends within a frame, an error with the cause io.ErrUnexpectedEOF is returned. Other errors
+// of the underlying io.Reader are wrapped and can be retrieved with errors.Cause.
+func (d *CapduDecoder) Decode() (*Capdu, error) {
+	prefix := make([]byte, LenFramePrefix)
+
+	if _, err := io.ReadFull(d.r, prefix); err != nil {
+		if err == io.EOF {
+			return nil, err
+		}
+
+		return nil, errors.Wrapf(err, "%s: read length prefix", packageTag)
+	}
+
+	frame := make([]byte, binary.BigEndian.Uint16(prefix))
+
+	if _, err := io.ReadFull(d.r, frame); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+
+		return nil, errors.Wrapf(err, "%s: read frame of %d byte", packageTag, len(frame))
+	}
+
+	c, err := ParseCapdu(frame)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s: invalid frame", packageTag)
+	}
+
+	return c, nil
+}
+
+// ParseRapduBatch parses a buffer of concatenated Response APDUs, each prefixed with its length encoded as two byte
+// big-endian value, and returns the Rapdus in order.
+// If a segment is truncated or can't be parsed, the Rapdus that have been parsed successfully so far are returned
+// together with an error that states their number.
+func ParseRapduBatch(b []byte) ([]*Rapdu, error) {
+	rapdus := make([]*Rapdu, 0)
+
+	for len(b) > 0 {
+		if len(b) < LenFramePrefix {
+			return rapdus, errors.Errorf("%s: truncated length prefix after %d parsed Rapdus", packageTag, len(rapdus))
+		}
+
+		segLen := int(binary.BigEndian.Uint16(b))
+		b = b[LenFramePrefix:]
+
+		if len(b) < segLen {
+			return rapdus, errors.Errorf("%s: truncated segment after %d parsed Rapdus - length prefix indicates %d byte, got %d", packageTag, len(rapdus), segLen, len(b))
+		}
+
+		r, err := ParseRapdu(b[:segLen])
+		if err != nil {
+			return rapdus, errors.Wrapf(err, "%s: invalid segment after %d parsed Rapdus", packageTag, len(rapdus))
+		}
+
+		rapdus = append(rapdus, r)
+		b = b[segLen:]
+	}
+
+	return rapdus, nil
+}
+
+// ReadCapdu reads a single Command APDU from r until io.EOF, calls ParseCapdu and returns a Capdu. Short reads are
+// handled by reading until io.EOF. Since the encoding of ISO 7816-4 is not self-delimiting, the end of the Capdu can't
+// be determined from its header or length fields: the byte following the header is either an Le (Case 2), an Lc
+// (Case 3 and 4) or, for Case 1, belongs to whatever follows. Therefore r must supply exactly one Capdu, e.g. a
+// request body or a datagram. Use a CapduEncoder and a CapduDecoder for streams of several Capdus.
+// An error is returned if r returns an error other than io.EOF or supplies more than MaxLenCapdu byte.
+func ReadCapdu(r io.Reader) (*Capdu, error) {
+	b, err := ioutil.ReadAll(io.LimitReader(r, int64(MaxLenCapdu)+1))
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s: read Capdu", packageTag)
+	}
+
+	if len(b) > MaxLenCapdu {
+		return nil, errors.Errorf("%s: reader supplies more than the maximum Capdu length of %d byte", packageTag, MaxLenCapdu)
+	}
+
+	return ParseCapdu(b)
+}
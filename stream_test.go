@@ -0,0 +1,488 @@
+package apdu
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"testing/iotest"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+type failingWriter struct {
+	err error
+}
+
+func (f *failingWriter) Write(p []byte) (int, error) {
+	return 0, f.err
+}
+
+func TestCapduEncoder_Encode(t *testing.T) {
+	tests := []struct {
+		name    string
+		capdus  []*Capdu
+		want    []byte
+		wantErr bool
+	}{
+		{
+			name:    "single Capdu",
+			capdus:  []*Capdu{{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0x01, 0x02}, Ne: 256}},
+			want:    []byte{0x00, 0x08, 0x00, 0xA4, 0x04, 0x00, 0x02, 0x01, 0x02, 0x00},
+			wantErr: false,
+		},
+		{
+			name: "multiple Capdus",
+			capdus: []*Capdu{
+				{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00},
+				{Cla: 0x80, Ins: 0xCA, P1: 0x00, P2: 0x66, Ne: 256},
+			},
+			want:    []byte{0x00, 0x04, 0x00, 0xA4, 0x04, 0x00, 0x00, 0x05, 0x80, 0xCA, 0x00, 0x66, 0x00},
+			wantErr: false,
+		},
+		{
+			name:    "error: invalid Capdu",
+			capdus:  []*Capdu{{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Ne: 65537}},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: Capdu exceeds frame length",
+			capdus:  []*Capdu{{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: make([]byte, MaxLenCommandDataExtended), Ne: 256}},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			e := NewCapduEncoder(buf)
+
+			var err error
+
+			for _, c := range tt.capdus {
+				if err = e.Encode(c); err != nil {
+					break
+				}
+			}
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Encode() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+			if !reflect.DeepEqual(buf.Bytes(), tt.want) {
+				t.Errorf("Encode() got = %X, want %X", buf.Bytes(), tt.want)
+			}
+		})
+	}
+}
+
+func TestCapduEncoder_Encode_WriterError(t *testing.T) {
+	writeErr := errors.New("connection reset")
+	e := NewCapduEncoder(&failingWriter{err: writeErr})
+
+	err := e.Encode(&Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00})
+	if pkgerrors.Cause(err) != writeErr {
+		t.Errorf("Encode() error = %v, want cause %v", err, writeErr)
+	}
+}
+
+func TestCapdu_WriteFramed_MaxLenFrame(t *testing.T) {
+	// header, extended Lc and data of an extended Case 3 command
+	lenDataMax := MaxLenFrame - LenHeader - LenLCExtended
+
+	tests := []struct {
+		name    string
+		capdu   *Capdu
+		wantN   int
+		wantErr bool
+	}{
+		{
+			name:    "maximum frame length",
+			capdu:   &Capdu{Cla: 0x00, Ins: 0xD6, P1: 0x00, P2: 0x00, Data: make([]byte, lenDataMax)},
+			wantN:   LenFramePrefix + MaxLenFrame,
+			wantErr: false,
+		},
+		{
+			name:    "error: maximum frame length exceeded by one byte",
+			capdu:   &Capdu{Cla: 0x00, Ins: 0xD6, P1: 0x00, P2: 0x00, Data: make([]byte, lenDataMax+1)},
+			wantN:   0,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+
+			n, err := tt.capdu.WriteFramed(buf)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("WriteFramed() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if n != tt.wantN || buf.Len() != tt.wantN {
+				t.Errorf("WriteFramed() got n = %d and %d byte written, want %d", n, buf.Len(), tt.wantN)
+			}
+
+			if err := NewCapduEncoder(&bytes.Buffer{}).Encode(tt.capdu); (err != nil) != tt.wantErr {
+				t.Errorf("Encode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCapdu_WriteFramed(t *testing.T) {
+	tests := []struct {
+		name    string
+		capdu   *Capdu
+		wantN   int
+		wantErr bool
+	}{
+		{
+			name:    "Case 1",
+			capdu:   &Capdu{Cla: 0x00, Ins: 0x70, P1: 0x00, P2: 0x00},
+			wantN:   6,
+			wantErr: false,
+		},
+		{
+			name:    "Case 4",
+			capdu:   &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0xA0, 0x00, 0x00, 0x01, 0x51}, Ne: 256},
+			wantN:   13,
+			wantErr: false,
+		},
+		{
+			name:    "extended Case 4",
+			capdu:   &Capdu{Cla: 0x00, Ins: 0xD6, P1: 0x00, P2: 0x00, Data: make([]byte, 300), Ne: 65536},
+			wantN:   LenFramePrefix + LenHeader + LenLCExtended + 300 + 2,
+			wantErr: false,
+		},
+		{
+			name:    "error: invalid Capdu",
+			capdu:   &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 65537},
+			wantN:   0,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+
+			n, err := tt.capdu.WriteFramed(buf)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("WriteFramed() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if n != tt.wantN || n != buf.Len() {
+				t.Errorf("WriteFramed() got n = %d, buffer len = %d, want %d", n, buf.Len(), tt.wantN)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			// decode the frame
+			frame := buf.Bytes()
+			if frameLen := int(frame[0])<<8 | int(frame[1]); frameLen != len(frame)-LenFramePrefix {
+				t.Fatalf("WriteFramed() length prefix %d does not match frame length %d", frameLen, len(frame)-LenFramePrefix)
+			}
+
+			got, err := ParseCapdu(frame[LenFramePrefix:])
+			if err != nil {
+				t.Fatalf("ParseCapdu() error = %v", err)
+			}
+
+			if !got.Equal(tt.capdu) {
+				t.Errorf("WriteFramed() round trip got = %v, want %v", got, tt.capdu)
+			}
+		})
+	}
+}
+
+func TestCapdu_WriteFramed_WriterError(t *testing.T) {
+	writeErr := errors.New("connection reset")
+	c := &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00}
+
+	n, err := c.WriteFramed(&failingWriter{err: writeErr})
+	if pkgerrors.Cause(err) != writeErr {
+		t.Errorf("WriteFramed() error = %v, want cause %v", err, writeErr)
+	}
+
+	if n != 0 {
+		t.Errorf("WriteFramed() got n = %d, want 0", n)
+	}
+}
+
+func TestCapduDecoder_Decode(t *testing.T) {
+	tests := []struct {
+		name      string
+		b         []byte
+		want      []*Capdu
+		wantCause error
+	}{
+		{
+			name:      "empty stream",
+			b:         []byte{},
+			want:      nil,
+			wantCause: io.EOF,
+		},
+		{
+			name: "multiple Capdus",
+			b:    []byte{0x00, 0x04, 0x00, 0xA4, 0x04, 0x00, 0x00, 0x05, 0x80, 0xCA, 0x00, 0x66, 0x00},
+			want: []*Capdu{
+				{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00},
+				{Cla: 0x80, Ins: 0xCA, P1: 0x00, P2: 0x66, Ne: 256},
+			},
+			wantCause: io.EOF,
+		},
+		{
+			name:      "error: truncated length prefix",
+			b:         []byte{0x00, 0x04, 0x00, 0xA4, 0x04, 0x00, 0x00},
+			want:      []*Capdu{{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00}},
+			wantCause: io.ErrUnexpectedEOF,
+		},
+		{
+			name:      "error: truncated frame",
+			b:         []byte{0x00, 0x05, 0x80, 0xCA, 0x00},
+			want:      nil,
+			wantCause: io.ErrUnexpectedEOF,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, r := range []io.Reader{bytes.NewReader(tt.b), iotest.OneByteReader(bytes.NewReader(tt.b))} {
+				d := NewCapduDecoder(r)
+
+				var (
+					got []*Capdu
+					err error
+				)
+
+				for {
+					var c *Capdu
+
+					if c, err = d.Decode(); err != nil {
+						break
+					}
+
+					got = append(got, c)
+				}
+
+				if pkgerrors.Cause(err) != tt.wantCause {
+					t.Errorf("Decode() error = %v, want cause %v", err, tt.wantCause)
+				}
+
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("Decode() got = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCapduDecoder_Decode_InvalidFrame(t *testing.T) {
+	d := NewCapduDecoder(bytes.NewReader([]byte{0x00, 0x02, 0x00, 0xA4}))
+
+	if _, err := d.Decode(); err == nil || pkgerrors.Cause(err) == io.EOF {
+		t.Errorf("Decode() error = %v, want parse error", err)
+	}
+}
+
+func TestCapduDecoder_Decode_RoundTrip(t *testing.T) {
+	capdus := []*Capdu{
+		{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0xA0, 0x00, 0x00, 0x01, 0x51}, Ne: 256},
+		{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 65536},
+		{Cla: 0x00, Ins: 0xD6, P1: 0x00, P2: 0x00, Data: make([]byte, 300)},
+	}
+
+	buf := &bytes.Buffer{}
+	e := NewCapduEncoder(buf)
+
+	for _, c := range capdus {
+		if err := e.Encode(c); err != nil {
+			t.Fatalf("Encode() error = %v", err)
+		}
+	}
+
+	d := NewCapduDecoder(buf)
+
+	for i, want := range capdus {
+		got, err := d.Decode()
+		if err != nil {
+			t.Fatalf("Decode() error = %v", err)
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Decode() Capdu %d got = %v, want %v", i, got, want)
+		}
+	}
+
+	if _, err := d.Decode(); err != io.EOF {
+		t.Errorf("Decode() error = %v, want %v", err, io.EOF)
+	}
+}
+
+func TestCapduDecoder_Decode_ReaderError(t *testing.T) {
+	d := NewCapduDecoder(iotest.TimeoutReader(bytes.NewReader([]byte{0x00, 0x04, 0x00, 0xA4, 0x04, 0x00})))
+
+	if _, err := d.Decode(); pkgerrors.Cause(err) != iotest.ErrTimeout {
+		t.Errorf("Decode() error = %v, want cause %v", err, iotest.ErrTimeout)
+	}
+}
+
+func TestParseRapduBatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		b       []byte
+		want    []*Rapdu
+		wantErr bool
+	}{
+		{
+			name:    "empty",
+			b:       []byte{},
+			want:    []*Rapdu{},
+			wantErr: false,
+		},
+		{
+			name: "two Rapdus of differing lengths",
+			b:    []byte{0x00, 0x02, 0x90, 0x00, 0x00, 0x05, 0x01, 0x02, 0x03, 0x61, 0x10},
+			want: []*Rapdu{
+				{SW1: 0x90, SW2: 0x00},
+				{Data: []byte{0x01, 0x02, 0x03}, SW1: 0x61, SW2: 0x10},
+			},
+			wantErr: false,
+		},
+		{
+			name: "error: truncated final segment",
+			b:    []byte{0x00, 0x02, 0x90, 0x00, 0x00, 0x05, 0x01, 0x02, 0x03},
+			want: []*Rapdu{
+				{SW1: 0x90, SW2: 0x00},
+			},
+			wantErr: true,
+		},
+		{
+			name: "error: truncated length prefix",
+			b:    []byte{0x00, 0x02, 0x90, 0x00, 0x00},
+			want: []*Rapdu{
+				{SW1: 0x90, SW2: 0x00},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "error: invalid segment",
+			b:       []byte{0x00, 0x01, 0x90},
+			want:    []*Rapdu{},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRapduBatch(tt.b)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseRapduBatch() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRapduBatch() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadCapdu(t *testing.T) {
+	tests := []struct {
+		name    string
+		b       []byte
+		want    *Capdu
+		wantErr bool
+	}{
+		{
+			name:    "Case 1",
+			b:       []byte{0x00, 0xA4, 0x04, 0x00},
+			want:    &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00},
+			wantErr: false,
+		},
+		{
+			name:    "Case 2 standard",
+			b:       []byte{0x80, 0xCA, 0x00, 0x66, 0x00},
+			want:    &Capdu{Cla: 0x80, Ins: 0xCA, P1: 0x00, P2: 0x66, Ne: 256},
+			wantErr: false,
+		},
+		{
+			name:    "Case 3 standard",
+			b:       []byte{0x00, 0xD6, 0x00, 0x00, 0x02, 0x01, 0x02},
+			want:    &Capdu{Cla: 0x00, Ins: 0xD6, P1: 0x00, P2: 0x00, Data: []byte{0x01, 0x02}},
+			wantErr: false,
+		},
+		{
+			name:    "Case 4 standard",
+			b:       []byte{0x80, 0xF2, 0xE0, 0x02, 0x02, 0x4F, 0x00, 0x00},
+			want:    &Capdu{Cla: 0x80, Ins: 0xF2, P1: 0xE0, P2: 0x02, Data: []byte{0x4F, 0x00}, Ne: 256},
+			wantErr: false,
+		},
+		{
+			name:    "Case 2 extended",
+			b:       []byte{0x00, 0xB0, 0x00, 0x00, 0x00, 0x00, 0x00},
+			want:    &Capdu{Cla: 0x00, Ins: 0xB0, P1: 0x00, P2: 0x00, Ne: 65536},
+			wantErr: false,
+		},
+		{
+			name:    "Case 4 extended",
+			b:       []byte{0x00, 0x2A, 0x80, 0x86, 0x00, 0x00, 0x02, 0x01, 0x02, 0x01, 0x00},
+			want:    &Capdu{Cla: 0x00, Ins: 0x2A, P1: 0x80, P2: 0x86, Data: []byte{0x01, 0x02}, Ne: 256},
+			wantErr: false,
+		},
+		{
+			name:    "error: empty",
+			b:       []byte{},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: truncated",
+			b:       []byte{0x00, 0xD6, 0x00, 0x00, 0x03, 0x01, 0x02},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: too long",
+			b:       make([]byte, MaxLenCapdu+1),
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, r := range []io.Reader{bytes.NewReader(tt.b), iotest.OneByteReader(bytes.NewReader(tt.b))} {
+				got, err := ReadCapdu(r)
+				if (err != nil) != tt.wantErr {
+					t.Errorf("ReadCapdu() error = %v, wantErr %v", err, tt.wantErr)
+
+					return
+				}
+
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("ReadCapdu() got = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestReadCapdu_ReaderError(t *testing.T) {
+	_, err := ReadCapdu(iotest.TimeoutReader(bytes.NewReader([]byte{0x00, 0xA4, 0x04, 0x00})))
+	if pkgerrors.Cause(err) != iotest.ErrTimeout {
+		t.Errorf("ReadCapdu() error = %v, want cause %v", err, iotest.ErrTimeout)
+	}
+}
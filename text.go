@@ -0,0 +1,64 @@
+package apdu
+
+import (
+	"encoding/hex"
+	"strings"
+)
+
+// MarshalText implements encoding.TextMarshaler and returns the uppercase hex representation of the Capdu, which
+// allows to specify Capdus e.g. in configuration files or as flag values. Unlike String, MarshalText never redacts
+// the command data (see RedactSensitive), since the result must be parseable by UnmarshalText.
+//
+// Since MarshalText is used by encoding/json, a *Capdu is encoded as JSON string of its hex representation instead of
+// a JSON object of its fields, e.g. "00A4040000", and Meta is not encoded. Encoding fails for Capdus that cannot be
+// encoded by Bytes, e.g. for a negative Ne.
+func (c *Capdu) MarshalText() ([]byte, error) {
+	b, err := c.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(strings.ToUpper(hex.EncodeToString(b))), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler and parses the hex representation of a Capdu with
+// ParseCapduHexString. All fields of the Capdu are replaced. The Capdu is not modified if an error is returned.
+// Since UnmarshalText is used by encoding/json, a Capdu is decoded from a JSON string of its hex representation and
+// JSON objects of its fields are rejected.
+func (c *Capdu) UnmarshalText(text []byte) error {
+	parsed, err := ParseCapduHexString(string(text))
+	if err != nil {
+		return err
+	}
+
+	*c = *parsed
+
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler and returns the uppercase hex representation of the Rapdu.
+// Since MarshalText is used by encoding/json, a *Rapdu is encoded as JSON string of its hex representation instead of
+// a JSON object of its fields, e.g. "9000".
+func (r *Rapdu) MarshalText() ([]byte, error) {
+	s, err := r.String()
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(s), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler and parses the hex representation of a Rapdu with
+// ParseRapduHexString. All fields of the Rapdu are replaced. The Rapdu is not modified if an error is returned.
+// Since UnmarshalText is used by encoding/json, a Rapdu is decoded from a JSON string of its hex representation and
+// JSON objects of its fields are rejected.
+func (r *Rapdu) UnmarshalText(text []byte) error {
+	parsed, err := ParseRapduHexString(string(text))
+	if err != nil {
+		return err
+	}
+
+	*r = *parsed
+
+	return nil
+}
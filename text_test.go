@@ -0,0 +1,277 @@
+package apdu
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+var (
+	_ encoding.TextMarshaler   = (*Capdu)(nil)
+	_ encoding.TextUnmarshaler = (*Capdu)(nil)
+	_ encoding.TextMarshaler   = (*Rapdu)(nil)
+	_ encoding.TextUnmarshaler = (*Rapdu)(nil)
+)
+
+func TestCapdu_MarshalText(t *testing.T) {
+	tests := []struct {
+		name    string
+		capdu   *Capdu
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "Case 4",
+			capdu:   &Capdu{Cla: 0x80, Ins: 0xF2, P1: 0xE0, P2: 0x02, Data: []byte{0x4F, 0x00}, Ne: 256},
+			want:    "80F2E002024F0000",
+			wantErr: false,
+		},
+		{
+			name:    "sensitive data is not redacted",
+			capdu:   &Capdu{Cla: 0x00, Ins: 0x20, P1: 0x00, P2: 0x80, Data: []byte{0x31, 0x32}},
+			want:    "00200080023132",
+			wantErr: false,
+		},
+		{
+			name:    "error: invalid Ne",
+			capdu:   &Capdu{Cla: 0x00, Ins: 0xB0, Ne: -1},
+			want:    "",
+			wantErr: true,
+		},
+	}
+
+	defer func(redact bool) { RedactSensitive = redact }(RedactSensitive)
+
+	RedactSensitive = true
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.capdu.MarshalText()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("MarshalText() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("MarshalText() got = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapdu_UnmarshalText(t *testing.T) {
+	tests := []struct {
+		name    string
+		capdu   *Capdu
+		text    string
+		want    *Capdu
+		wantErr bool
+	}{
+		{
+			name:    "resets fields",
+			capdu:   &Capdu{Cla: 0x80, Ins: 0xCA, P1: 0x9F, P2: 0x7F, Data: []byte{0x01}, Ne: 256},
+			text:    "00A40400",
+			want:    &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00},
+			wantErr: false,
+		},
+		{
+			name:    "Case 4",
+			capdu:   &Capdu{},
+			text:    "80F2E002024F0000",
+			want:    &Capdu{Cla: 0x80, Ins: 0xF2, P1: 0xE0, P2: 0x02, Data: []byte{0x4F, 0x00}, Ne: 256},
+			wantErr: false,
+		},
+		{
+			name:    "error: invalid hex",
+			capdu:   &Capdu{Cla: 0x00, Ins: 0xB0},
+			text:    "00A4040G",
+			want:    &Capdu{Cla: 0x00, Ins: 0xB0},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.capdu.UnmarshalText([]byte(tt.text))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("UnmarshalText() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if !reflect.DeepEqual(tt.capdu, tt.want) {
+				t.Errorf("UnmarshalText() got = %v, want %v", tt.capdu, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapdu_MarshalText_RoundTrip(t *testing.T) {
+	capdus := []*Capdu{
+		{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00},
+		{Cla: 0x80, Ins: 0xCA, P1: 0x00, P2: 0x66, Ne: 256},
+		{Cla: 0x00, Ins: 0xD6, P1: 0x00, P2: 0x00, Data: []byte{0x01, 0x02}},
+		{Cla: 0x00, Ins: 0xAA, P1: 0xBB, P2: 0xCC, Data: make([]byte, 300), Ne: 65536},
+	}
+
+	for _, c := range capdus {
+		text, err := c.MarshalText()
+		if err != nil {
+			t.Fatalf("MarshalText() error = %v", err)
+		}
+
+		got := &Capdu{}
+		if err := got.UnmarshalText(text); err != nil {
+			t.Fatalf("UnmarshalText() error = %v", err)
+		}
+
+		if !got.Equal(c) {
+			t.Errorf("round trip of %s got = %v, want %v", text, got, c)
+		}
+	}
+}
+
+func TestCapdu_JSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		capdu   *Capdu
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "hex string instead of object",
+			capdu:   &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0xA0, 0x00}, Ne: 256, Meta: map[string]interface{}{"id": 1}},
+			want:    `"00A4040002A00000"`,
+			wantErr: false,
+		},
+		{
+			name:    "error: invalid Ne",
+			capdu:   &Capdu{Cla: 0x00, Ins: 0xB0, Ne: -1},
+			want:    "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.capdu)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("json.Marshal() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("json.Marshal() got = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCapdu_JSON_Unmarshal(t *testing.T) {
+	got := &Capdu{}
+	if err := json.Unmarshal([]byte(`"00A4040002A00000"`), got); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+
+	want := &Capdu{Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00, Data: []byte{0xA0, 0x00}, Ne: 256}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("json.Unmarshal() got = %v, want %v", got, want)
+	}
+
+	if err := json.Unmarshal([]byte(`{"Cla":0,"Ins":164,"P1":4,"P2":0}`), &Capdu{}); err == nil {
+		t.Errorf("json.Unmarshal() of a JSON object expected error")
+	}
+}
+
+func TestRapdu_JSON(t *testing.T) {
+	got, err := json.Marshal(&Rapdu{Data: []byte{0x6F, 0x00}, SW1: 0x90, SW2: 0x00})
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	if want := `"6F009000"`; string(got) != want {
+		t.Errorf("json.Marshal() got = %s, want %s", got, want)
+	}
+
+	r := &Rapdu{}
+	if err := json.Unmarshal(got, r); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+
+	if want := (&Rapdu{Data: []byte{0x6F, 0x00}, SW1: 0x90, SW2: 0x00}); !reflect.DeepEqual(r, want) {
+		t.Errorf("json.Unmarshal() got = %v, want %v", r, want)
+	}
+}
+
+func TestRapdu_UnmarshalText(t *testing.T) {
+	tests := []struct {
+		name    string
+		rapdu   *Rapdu
+		text    string
+		want    *Rapdu
+		wantErr bool
+	}{
+		{
+			name:    "resets fields",
+			rapdu:   &Rapdu{Data: []byte{0x01, 0x02}, SW1: 0x90, SW2: 0x00},
+			text:    "6A82",
+			want:    &Rapdu{SW1: 0x6A, SW2: 0x82},
+			wantErr: false,
+		},
+		{
+			name:    "data and SW",
+			rapdu:   &Rapdu{},
+			text:    "0102039000",
+			want:    &Rapdu{Data: []byte{0x01, 0x02, 0x03}, SW1: 0x90, SW2: 0x00},
+			wantErr: false,
+		},
+		{
+			name:    "error: invalid hex",
+			rapdu:   &Rapdu{SW1: 0x90, SW2: 0x00},
+			text:    "90XY",
+			want:    &Rapdu{SW1: 0x90, SW2: 0x00},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rapdu.UnmarshalText([]byte(tt.text))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("UnmarshalText() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if !reflect.DeepEqual(tt.rapdu, tt.want) {
+				t.Errorf("UnmarshalText() got = %v, want %v", tt.rapdu, tt.want)
+			}
+		})
+	}
+}
+
+func TestRapdu_MarshalText_RoundTrip(t *testing.T) {
+	rapdus := []*Rapdu{
+		{SW1: 0x90, SW2: 0x00},
+		{Data: []byte{0x01, 0x02, 0x03}, SW1: 0x62, SW2: 0x83},
+	}
+
+	for _, r := range rapdus {
+		text, err := r.MarshalText()
+		if err != nil {
+			t.Fatalf("MarshalText() error = %v", err)
+		}
+
+		got := &Rapdu{}
+		if err := got.UnmarshalText(text); err != nil {
+			t.Fatalf("UnmarshalText() error = %v", err)
+		}
+
+		if !reflect.DeepEqual(got, r) {
+			t.Errorf("round trip of %s got = %v, want %v", text, got, r)
+		}
+	}
+}
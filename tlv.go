@@ -0,0 +1,230 @@
+package apdu
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// lenAssumedTag is the length of the tag that NeForTLV assumes for the BER-TLV data object.
+const lenAssumedTag int = 2
+
+// NeForTLV returns Ne for reading a BER-TLV data object with a value of valueLen byte.
+// The returned Ne includes the overhead of the tag and the length field. NeForTLV assumes a tag of 2 byte,
+// which covers all tags of ISO 7816-4, EMV and GlobalPlatform data objects, and a length field encoded in the shortest
+// form according to BER (1 byte for lengths up to 127, 2 byte up to 255 and 3 byte up to 65535).
+// For a one byte tag, the returned Ne exceeds the actual length by one.
+// The result is capped at MaxLenResponseDataExtended and NeForTLV returns 0 for a negative valueLen.
+func NeForTLV(valueLen int) int {
+	if valueLen < 0 {
+		return 0
+	}
+
+	ne := lenAssumedTag + lenLengthField(valueLen) + valueLen
+	if ne > MaxLenResponseDataExtended {
+		return MaxLenResponseDataExtended
+	}
+
+	return ne
+}
+
+// lenLengthField returns the number of bytes of the shortest BER encoding of the length field for the given length.
+func lenLengthField(length int) int {
+	switch {
+	case length < 0x80:
+		return 1
+	case length <= 0xFF:
+		return 2
+	case length <= 0xFFFF:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// tlv is a BER-TLV encoded data object.
+type tlv struct {
+	tag         uint32 // tag is the tag of up to four byte.
+	constructed bool   // constructed is true if the value consists of further data objects.
+	length      int    // length is the length that is declared by the length field.
+	value       []byte // value is the value field, which might be shorter than length for truncated data.
+}
+
+// parseTag parses a BER-TLV tag at the beginning of b and returns the tag, whether it is constructed and the number of
+// bytes that were consumed.
+func parseTag(b []byte) (uint32, bool, int, error) {
+	if len(b) == 0 {
+		return 0, false, 0, errors.Errorf("%s: missing tag", packageTag)
+	}
+
+	tag := uint32(b[0])
+	constructed := b[0]&0x20 == 0x20
+	n := 1
+
+	// subsequent bytes follow if bits 5-1 of the first byte are set
+	if b[0]&0x1F == 0x1F {
+		for {
+			if n == len(b) {
+				return 0, false, 0, errors.Errorf("%s: tag is truncated", packageTag)
+			}
+
+			if n == 4 {
+				return 0, false, 0, errors.Errorf("%s: tag exceeds maximum length of 4 byte", packageTag)
+			}
+
+			tag = tag<<8 | uint32(b[n])
+			n++
+
+			// bit 8 indicates another subsequent byte
+			if b[n-1]&0x80 == 0x00 {
+				break
+			}
+		}
+	}
+
+	return tag, constructed, n, nil
+}
+
+// formatTag returns the tag hex encoded with two uppercase digits per tag byte, e.g. "04" or "9F38". The number of
+// tag bytes is unambiguous, since the first byte of a multi-byte tag is never zero.
+func formatTag(tag uint32) string {
+	n := 1
+	for t := tag >> 8; t != 0; t >>= 8 {
+		n++
+	}
+
+	return fmt.Sprintf("%0*X", 2*n, tag)
+}
+
+// parseLength parses a BER-TLV length field at the beginning of b and returns the length and the number of bytes that
+// were consumed.
+func parseLength(b []byte) (int, int, error) {
+	if len(b) == 0 {
+		return 0, 0, errors.Errorf("%s: missing length", packageTag)
+	}
+
+	if b[0] < 0x80 {
+		return int(b[0]), 1, nil
+	}
+
+	// long form: bits 7-1 encode the number of subsequent length bytes
+	numBytes := int(b[0] & 0x7F)
+	if numBytes == 0 || numBytes > 3 {
+		return 0, 0, errors.Errorf("%s: invalid length field 0x%02X", packageTag, b[0])
+	}
+
+	if len(b) < 1+numBytes {
+		return 0, 0, errors.Errorf("%s: length field is truncated", packageTag)
+	}
+
+	length := 0
+	for _, l := range b[1 : 1+numBytes] {
+		length = length<<8 | int(l)
+	}
+
+	return length, 1 + numBytes, nil
+}
+
+// parseTLVHeader parses the tag and length field of a BER-TLV data object at the beginning of b and returns the data
+// object with an empty value and the number of bytes that were consumed.
+func parseTLVHeader(b []byte) (tlv, int, error) {
+	tag, constructed, tagLen, err := parseTag(b)
+	if err != nil {
+		return tlv{}, 0, err
+	}
+
+	length, lenLen, err := parseLength(b[tagLen:])
+	if err != nil {
+		return tlv{}, 0, err
+	}
+
+	return tlv{tag: tag, constructed: constructed, length: length}, tagLen + lenLen, nil
+}
+
+// parseTLVs parses a sequence of BER-TLV data objects.
+func parseTLVs(b []byte) ([]tlv, error) {
+	var tlvs []tlv
+
+	for len(b) > 0 {
+		t, n, err := parseTLVHeader(b)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(b)-n < t.length {
+			return nil, errors.Errorf("%s: value of tag %s is truncated - length indicates %d byte, got %d", packageTag, formatTag(t.tag), t.length, len(b)-n)
+		}
+
+		t.value = b[n : n+t.length]
+		tlvs = append(tlvs, t)
+		b = b[n+t.length:]
+	}
+
+	return tlvs, nil
+}
+
+// findTLV searches the BER-TLV encoded data recursively for the first data object with the given tag and returns it.
+func findTLV(b []byte, tag uint32) (tlv, bool, error) {
+	tlvs, err := parseTLVs(b)
+	if err != nil {
+		return tlv{}, false, err
+	}
+
+	for _, t := range tlvs {
+		if t.tag == tag {
+			return t, true, nil
+		}
+
+		if t.constructed {
+			found, ok, err := findTLV(t.value, tag)
+			if err != nil {
+				return tlv{}, false, err
+			}
+
+			if ok {
+				return found, true, nil
+			}
+		}
+	}
+
+	return tlv{}, false, nil
+}
+
+// TLVMap parses Data as sequence of BER-TLV data objects and returns a map of the values of all data objects keyed by
+// their tag path, which consists of the hex encoded tags of the enclosing constructed data objects and the tag of the
+// data object separated by dots, e.g. "6F.A5.50". Constructed data objects are included with their complete value.
+// If a tag path occurs more than once, the value of the first occurrence is returned.
+// An empty map is returned for empty Data and an error if Data is not well-formed.
+func (c *Capdu) TLVMap() (map[string][]byte, error) {
+	m := make(map[string][]byte)
+
+	if err := addTLVPaths(m, "", c.Data); err != nil {
+		return nil, errors.Wrapf(err, "%s: invalid TLV in Capdu.Data", packageTag)
+	}
+
+	return m, nil
+}
+
+// addTLVPaths parses b as sequence of BER-TLV data objects and adds their values recursively to m.
+func addTLVPaths(m map[string][]byte, prefix string, b []byte) error {
+	tlvs, err := parseTLVs(b)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range tlvs {
+		path := prefix + formatTag(t.tag)
+
+		if _, ok := m[path]; !ok {
+			m[path] = t.value
+		}
+
+		if t.constructed {
+			if err := addTLVPaths(m, path+".", t.value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,280 @@
+package apdu
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNeForTLV(t *testing.T) {
+	tests := []struct {
+		name     string
+		valueLen int
+		want     int
+	}{
+		{name: "negative", valueLen: -1, want: 0},
+		{name: "empty value", valueLen: 0, want: 3},
+		{name: "short form max", valueLen: 127, want: 130},
+		{name: "two byte length min", valueLen: 128, want: 132},
+		{name: "two byte length max", valueLen: 255, want: 259},
+		{name: "three byte length min", valueLen: 256, want: 261},
+		{name: "three byte length", valueLen: 65530, want: 65535},
+		{name: "capped", valueLen: 65535, want: 65536},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NeForTLV(tt.valueLen); got != tt.want {
+				t.Errorf("NeForTLV() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_formatTag(t *testing.T) {
+	tests := []struct {
+		name string
+		tag  uint32
+		want string
+	}{
+		{name: "one byte tag below 0x10", tag: 0x04, want: "04"},
+		{name: "one byte tag", tag: 0x6F, want: "6F"},
+		{name: "two byte tag", tag: 0x9F38, want: "9F38"},
+		{name: "two byte tag with zero subsequent byte", tag: 0x5F00, want: "5F00"},
+		{name: "three byte tag", tag: 0xBF8101, want: "BF8101"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatTag(tt.tag); got != tt.want {
+				t.Errorf("formatTag() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseTLVs(t *testing.T) {
+	tests := []struct {
+		name    string
+		b       []byte
+		want    []tlv
+		wantErr bool
+	}{
+		{
+			name:    "empty",
+			b:       nil,
+			want:    nil,
+			wantErr: false,
+		},
+		{
+			name: "primitive one byte tag",
+			b:    []byte{0x50, 0x02, 0x01, 0x02},
+			want: []tlv{
+				{tag: 0x50, length: 2, value: []byte{0x01, 0x02}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "multi byte tag and constructed",
+			b:    []byte{0x9F, 0x17, 0x01, 0x03, 0xA5, 0x02, 0x50, 0x00},
+			want: []tlv{
+				{tag: 0x9F17, length: 1, value: []byte{0x03}},
+				{tag: 0xA5, constructed: true, length: 2, value: []byte{0x50, 0x00}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "long form length",
+			b:    append([]byte{0x53, 0x81, 0x80}, make([]byte, 128)...),
+			want: []tlv{
+				{tag: 0x53, length: 128, value: make([]byte, 128)},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "error: truncated value",
+			b:       []byte{0x50, 0x03, 0x01, 0x02},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: truncated tag",
+			b:       []byte{0x9F},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: missing length",
+			b:       []byte{0x50},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: invalid length field",
+			b:       []byte{0x50, 0x80},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: truncated length field",
+			b:       []byte{0x50, 0x82, 0x01},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: tag too long",
+			b:       []byte{0x9F, 0x81, 0x81, 0x81, 0x01, 0x00},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTLVs(tt.b)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseTLVs() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseTLVs() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_findTLV(t *testing.T) {
+	fci := []byte{0x6F, 0x0B, 0x84, 0x02, 0xA0, 0x00, 0xA5, 0x05, 0x9F, 0x12, 0x02, 0x41, 0x42}
+
+	tests := []struct {
+		name    string
+		b       []byte
+		tag     uint32
+		want    tlv
+		want1   bool
+		wantErr bool
+	}{
+		{
+			name:    "nested",
+			b:       fci,
+			tag:     0x9F12,
+			want:    tlv{tag: 0x9F12, length: 2, value: []byte{0x41, 0x42}},
+			want1:   true,
+			wantErr: false,
+		},
+		{
+			name:    "not present",
+			b:       fci,
+			tag:     0x50,
+			want:    tlv{},
+			want1:   false,
+			wantErr: false,
+		},
+		{
+			name:    "error: malformed nested",
+			b:       []byte{0x6F, 0x02, 0x84, 0x05},
+			tag:     0x50,
+			want:    tlv{},
+			want1:   false,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, got1, err := findTLV(tt.b, tt.tag)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("findTLV() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findTLV() got = %v, want %v", got, tt.want)
+			}
+			if got1 != tt.want1 {
+				t.Errorf("findTLV() got1 = %v, want %v", got1, tt.want1)
+			}
+		})
+	}
+}
+
+func TestCapdu_TLVMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    map[string][]byte
+		wantErr bool
+	}{
+		{
+			name:    "empty",
+			data:    nil,
+			want:    map[string][]byte{},
+			wantErr: false,
+		},
+		{
+			name: "nested",
+			data: []byte{
+				0x6F, 0x10,
+				0x84, 0x02, 0xA0, 0x00,
+				0xA5, 0x0A,
+				0x50, 0x02, 0x41, 0x42,
+				0x9F, 0x38, 0x03, 0x9F, 0x1A, 0x02,
+			},
+			want: map[string][]byte{
+				"6F":         {0x84, 0x02, 0xA0, 0x00, 0xA5, 0x0A, 0x50, 0x02, 0x41, 0x42, 0x9F, 0x38, 0x03, 0x9F, 0x1A, 0x02},
+				"6F.84":      {0xA0, 0x00},
+				"6F.A5":      {0x50, 0x02, 0x41, 0x42, 0x9F, 0x38, 0x03, 0x9F, 0x1A, 0x02},
+				"6F.A5.50":   {0x41, 0x42},
+				"6F.A5.9F38": {0x9F, 0x1A, 0x02},
+			},
+			wantErr: false,
+		},
+		{
+			name: "tag below 0x10",
+			data: []byte{0x30, 0x03, 0x04, 0x01, 0xAA},
+			want: map[string][]byte{
+				"30":    {0x04, 0x01, 0xAA},
+				"30.04": {0xAA},
+			},
+			wantErr: false,
+		},
+		{
+			name: "sequence with duplicate tag",
+			data: []byte{0x5A, 0x01, 0x01, 0x5A, 0x01, 0x02, 0x5F, 0x20, 0x00},
+			want: map[string][]byte{
+				"5A":   {0x01},
+				"5F20": {},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "error: malformed nested TLV",
+			data:    []byte{0x6F, 0x03, 0x84, 0x05, 0xA0},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "error: truncated",
+			data:    []byte{0x6F, 0x10, 0x84},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Capdu{Cla: 0x00, Ins: 0xDA, P1: 0x01, P2: 0x02, Data: tt.data}
+
+			got, err := c.TLVMap()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("TLVMap() error = %v, wantErr %v", err, tt.wantErr)
+
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TLVMap() got = %X, want %X", got, tt.want)
+			}
+		})
+	}
+}